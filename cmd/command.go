@@ -10,6 +10,7 @@ import (
 	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
 )
 
 // CommandCmd holds the cmd flags
@@ -48,28 +49,42 @@ func (cmd *CommandCmd) Run(ctx context.Context, options *options.Options, log lo
 		return nil
 	}
 
+	sshClient, err := newSSHClient(ctx, options)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sshClient.Close()
+	}()
+
+	// Run the command
+	return devpodssh.Run(ctx, sshClient, command, os.Stdin, os.Stdout, os.Stderr, nil)
+}
+
+// newSSHClient connects to the instance with the private key from the machine folder
+func newSSHClient(ctx context.Context, options *options.Options) (*ssh.Client, error) {
 	// Get server IP
 	client := upcloud.NewUpCloud(options.Username, options.Password)
 	serverIP, err := client.GetServerIP(ctx, options.MachineID)
 	if err != nil {
-		return errors.Wrap(err, "get server ip")
+		return nil, errors.Wrap(err, "get server ip")
 	}
 
-	// Setup SSH client
-	privateKey, err := devpodssh.GetPrivateKeyRawBase(options.MachineFolder)
+	return dialSSH(serverIP, options.MachineFolder)
+}
+
+// dialSSH connects to a server with the private key from the machine folder
+func dialSSH(serverIP, machineFolder string) (*ssh.Client, error) {
+	privateKey, err := devpodssh.GetPrivateKeyRawBase(machineFolder)
 	if err != nil {
-		return errors.Wrap(err, "get private key")
+		return nil, errors.Wrap(err, "get private key")
 	}
 
 	// Use root user for SSH (as specified in provider.yaml)
-	sshClient, err := devpodssh.NewSSHClient("root", serverIP+":22", privateKey)
+	sshClient, err := devpodssh.NewSSHClient(upcloud.DefaultSSHUser, serverIP+":22", privateKey)
 	if err != nil {
-		return errors.Wrap(err, "create ssh client")
+		return nil, errors.Wrap(err, "create ssh client")
 	}
-	defer func() {
-		_ = sshClient.Close()
-	}()
 
-	// Run the command
-	return devpodssh.Run(ctx, sshClient, command, os.Stdin, os.Stdout, os.Stderr, nil)
+	return sshClient, nil
 }
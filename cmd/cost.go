@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// CostCmd holds the cost command flags
+type CostCmd struct {
+	Hours  float64
+	Owner  string
+	Format string
+}
+
+// costReport is the output of the cost command
+type costReport struct {
+	Estimate   *costEstimate   `json:"estimate" yaml:"estimate"`
+	Workspaces []workspaceCost `json:"workspaces" yaml:"workspaces"`
+	// Owners sums the accrued cost of the workspaces per owner
+	Owners map[string]float64 `json:"owners_accrued_eur" yaml:"owners_accrued_eur"`
+}
+
+// costEstimate is the estimate for the current options
+type costEstimate struct {
+	*upcloud.CostEstimate `yaml:",inline"`
+
+	RunningHourly float64 `json:"running_hourly_eur" yaml:"running_hourly_eur"`
+	StoppedHourly float64 `json:"stopped_hourly_eur" yaml:"stopped_hourly_eur"`
+	RunningHours  float64 `json:"running_hours_per_month" yaml:"running_hours_per_month"`
+	Monthly       float64 `json:"monthly_eur" yaml:"monthly_eur"`
+}
+
+// workspaceCost is the accrued cost of an existing workspace
+type workspaceCost struct {
+	MachineID    string   `json:"machine_id" yaml:"machine_id"`
+	Owner        string   `json:"owner,omitempty" yaml:"owner,omitempty"`
+	State        string   `json:"state" yaml:"state"`
+	Plan         string   `json:"plan" yaml:"plan"`
+	Zone         string   `json:"zone" yaml:"zone"`
+	AgeHours     *float64 `json:"age_hours,omitempty" yaml:"age_hours,omitempty"`
+	RunningHours float64  `json:"running_hours" yaml:"running_hours"`
+	Accrued      *float64 `json:"accrued_eur,omitempty" yaml:"accrued_eur,omitempty"`
+}
+
+// NewCostCmd defines the cost command
+func NewCostCmd() *cobra.Command {
+	cmd := &CostCmd{}
+	costCmd := &cobra.Command{
+		Use:   "cost",
+		Short: "Estimate workspace costs and report accrued costs",
+		Long: `Estimate the cost of a workspace with the current options and report the cost
+accrued by the existing workspaces in the account.
+
+The estimate covers the plan, the storage beyond what the plan includes priced
+by its tier, and the public IPv4 address. Cloud Native plans are only billed
+while running; storage and addresses are billed for as long as they exist.
+Storage and address prices come from UpCloud's price list for the zone.
+
+Accrued costs are based on each workspace's creation time and running hours,
+which the provider records in server labels. A workspace stopped from inside
+is counted as running until it is next started or stopped through the
+provider, so running hours are an upper bound. Workspaces created by older provider versions have no
+running hours. All amounts are estimates in euro excluding VAT.`,
+		Example: `  # Estimate the current options and list accrued costs
+  devpod-provider-upcloud cost
+
+  # Estimate a workspace used 8 hours a day on 22 days a month
+  devpod-provider-upcloud cost --hours 176
+
+  # Accrued costs of one user's workspaces as JSON
+  devpod-provider-upcloud cost --owner alice --format json`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnvInit()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options, log.Default)
+		},
+	}
+
+	costCmd.Flags().Float64Var(&cmd.Hours, "hours", upcloud.HoursPerMonth, "Running hours per month for the estimate")
+	costCmd.Flags().StringVar(&cmd.Owner, "owner", "", "Show only workspaces created by this user")
+	costCmd.Flags().StringVarP(&cmd.Format, "format", "f", "table", "Output format (table, json, yaml)")
+
+	return costCmd
+}
+
+// Run runs the command logic
+func (cmd *CostCmd) Run(ctx context.Context, options *options.Options, log log.Logger) error {
+	if cmd.Hours < 0 || cmd.Hours > upcloud.HoursPerMonth {
+		return fmt.Errorf("--hours must be between 0 and %d", upcloud.HoursPerMonth)
+	}
+
+	plans, err := config.LoadServerPlans()
+	if err != nil {
+		return errors.Wrap(err, "load plans")
+	}
+
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+
+	prices, err := client.GetZonePrices(ctx)
+	if err != nil {
+		log.Warnf("Could not fetch prices, storage and IP costs are not included: %v", err)
+		prices = map[string]upcloud.ZonePrices{}
+	}
+
+	estimate, err := cmd.estimate(options, plans, prices)
+	if err != nil {
+		return err
+	}
+
+	servers, err := client.ListManagedServers(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list servers")
+	}
+
+	report := &costReport{
+		Estimate:   estimate,
+		Workspaces: []workspaceCost{},
+		Owners:     map[string]float64{},
+	}
+	for i := range servers {
+		server := &servers[i]
+		if cmd.Owner != "" && server.Owner != cmd.Owner {
+			continue
+		}
+
+		workspace := accruedCost(server, plans, prices[server.Zone])
+		if workspace.Accrued != nil {
+			report.Owners[workspace.Owner] += *workspace.Accrued
+		}
+		report.Workspaces = append(report.Workspaces, workspace)
+	}
+
+	switch cmd.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		defer func() {
+			_ = encoder.Close()
+		}()
+		return encoder.Encode(report)
+	default:
+		outputCostReport(report)
+		return nil
+	}
+}
+
+// estimate prices a workspace with the current options
+func (cmd *CostCmd) estimate(options *options.Options, plans *config.ServerPlans, prices map[string]upcloud.ZonePrices) (*costEstimate, error) {
+	planID, err := upcloud.MapPlanName(options.Plan)
+	if err != nil {
+		return nil, err
+	}
+	plan, category, err := plans.GetPlanByID(planID)
+	if err != nil {
+		return nil, fmt.Errorf("no prices for plan %s: %w", planID, err)
+	}
+	storage, err := upcloud.ParseStorageSize(options.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := []upcloud.StorageInfo{{Size: storage, Tier: upcloud.GetStorageTier(planID)}}
+	estimate := upcloud.NewCostEstimate(plan, category, options.Zone, disks, prices[options.Zone])
+
+	return &costEstimate{
+		CostEstimate:  estimate,
+		RunningHourly: estimate.RunningHourly(),
+		StoppedHourly: estimate.StoppedHourly(),
+		RunningHours:  cmd.Hours,
+		Monthly:       estimate.Monthly(cmd.Hours),
+	}, nil
+}
+
+// accruedCost prices an existing workspace since its creation
+func accruedCost(server *upcloud.ManagedServer, plans *config.ServerPlans, prices upcloud.ZonePrices) workspaceCost {
+	workspace := workspaceCost{
+		MachineID:    server.MachineID,
+		Owner:        server.Owner,
+		State:        server.Status,
+		Plan:         server.Plan,
+		Zone:         server.Zone,
+		RunningHours: server.TotalRunningHours(),
+	}
+
+	if server.CreatedAt != nil {
+		age := time.Since(*server.CreatedAt).Hours()
+		workspace.AgeHours = &age
+	}
+
+	plan, category, err := plans.GetPlanByID(server.Plan)
+	if err != nil || server.CreatedAt == nil {
+		return workspace
+	}
+
+	estimate := upcloud.NewCostEstimate(plan, category, server.Zone, server.Storages, prices)
+	accrued := estimate.Accrued(time.Since(*server.CreatedAt), workspace.RunningHours)
+	workspace.Accrued = &accrued
+	return workspace
+}
+
+// outputCostReport outputs the cost report in table format
+func outputCostReport(report *costReport) {
+	estimate := report.Estimate
+
+	fmt.Println("Cost Estimate")
+	fmt.Println("=============")
+	fmt.Println()
+
+	billing := "billed while it exists"
+	if estimate.BilledWhenOnOnly {
+		billing = "billed while running"
+	}
+	fmt.Printf("Plan %s in %s (%s)\n", estimate.Plan, estimate.Zone, billing)
+	fmt.Printf("  €%.4f/hour, at most €%.2f/month\n", estimate.PlanHourly, estimate.PlanMonthly)
+	if estimate.PricesKnown {
+		fmt.Printf("Storage %d GB (%d GB beyond the plan)\n", estimate.StorageSize, estimate.StorageBilled)
+		fmt.Printf("  €%.4f/hour\n", estimate.StorageHourly)
+		fmt.Printf("Public IPv4 address\n")
+		fmt.Printf("  €%.4f/hour\n", estimate.IPv4Hourly)
+	} else {
+		fmt.Println("Storage and IP prices are unavailable and not included")
+	}
+	fmt.Println()
+	fmt.Printf("Running: €%.4f/hour, stopped: €%.4f/hour\n", estimate.RunningHourly, estimate.StoppedHourly)
+	fmt.Printf("Monthly at %.0f running hours: €%.2f\n", estimate.RunningHours, estimate.Monthly)
+	fmt.Println()
+
+	fmt.Println("Accrued Cost per Workspace")
+	fmt.Println("==========================")
+	fmt.Println()
+
+	if len(report.Workspaces) == 0 {
+		fmt.Println("No workspaces found")
+		return
+	}
+
+	fmt.Printf("%-32s %-12s %-8s %-22s %-8s %8s %8s %10s\n",
+		"MACHINE ID", "OWNER", "STATE", "PLAN", "ZONE", "AGE (h)", "RUN (h)", "ACCRUED")
+	for _, workspace := range report.Workspaces {
+		age, accrued := "-", "-"
+		if workspace.AgeHours != nil {
+			age = fmt.Sprintf("%.1f", *workspace.AgeHours)
+		}
+		if workspace.Accrued != nil {
+			accrued = fmt.Sprintf("€%.2f", *workspace.Accrued)
+		}
+		fmt.Printf("%-32s %-12s %-8s %-22s %-8s %8s %8.1f %10s\n",
+			workspace.MachineID, orDash(workspace.Owner), workspace.State, workspace.Plan, workspace.Zone,
+			age, workspace.RunningHours, accrued)
+	}
+
+	owners := make([]string, 0, len(report.Owners))
+	for owner := range report.Owners {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	fmt.Println()
+	fmt.Println("Accrued per owner:")
+	for _, owner := range owners {
+		fmt.Printf("  %-20s €%.2f\n", orDash(owner), report.Owners[owner])
+	}
+}
@@ -3,9 +3,13 @@ package cmd
 import (
 	"context"
 	"encoding/base64"
+	"os/user"
+	"time"
 
 	"github.com/loft-sh/devpod/pkg/ssh"
 	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/cloudinit"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
 	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
 	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
 	"github.com/pkg/errors"
@@ -49,6 +53,35 @@ func (cmd *CreateCmd) Run(ctx context.Context, options *options.Options, log log
 		return errors.Wrap(err, "decode public key")
 	}
 
+	// Parse user-supplied user data before anything is created
+	var extraUserData *cloudinit.Part
+	if options.UserData != "" {
+		extraUserData, err = cloudinit.ParseUserData(options.UserData)
+		if err != nil {
+			return errors.Wrap(err, "parse UPCLOUD_USER_DATA")
+		}
+	}
+
+	dockerInstall, err := cloudinit.ParseDockerInstall(options.DockerInstall)
+	if err != nil {
+		return errors.Wrap(err, "UPCLOUD_DOCKER_INSTALL")
+	}
+	if err := cloudinit.ValidateDockerVersion(options.DockerVersion); err != nil {
+		return errors.Wrap(err, "UPCLOUD_DOCKER_VERSION")
+	}
+	dockerDaemon, err := newDockerDaemon(options)
+	if err != nil {
+		return errors.Wrap(err, "docker daemon options")
+	}
+	swapSize, err := cloudinit.ParseSwapSize(options.SwapSize, recommendedSwap(options, log))
+	if err != nil {
+		return errors.Wrap(err, "UPCLOUD_SWAP_SIZE")
+	}
+	sysctl, err := cloudinit.Sysctl(options.SysctlProfile, options.Sysctl)
+	if err != nil {
+		return errors.Wrap(err, "sysctl options")
+	}
+
 	// Create server configuration
 	serverConfig := &upcloud.ServerConfig{
 		Hostname: options.MachineID,
@@ -58,7 +91,48 @@ func (cmd *CreateCmd) Run(ctx context.Context, options *options.Options, log log
 		Image:    options.Image,
 		Template: options.Template,
 		SSHKey:   string(publicKey),
-		UserData: GetCloudInitScript(options.MachineID),
+		Owner:    currentOwner(),
+
+		Encrypted: options.StorageEncryption,
+	}
+
+	// Report every configuration problem before creating anything
+	preflight := client.Preflight(ctx, serverConfig)
+	for _, warning := range preflight.Warnings {
+		log.Warn(warning)
+	}
+	if err := preflight.Err(); err != nil {
+		return err
+	}
+
+	// Create the server from the template preflight resolved and validated
+	template := preflight.Template
+	serverConfig.ResolvedTemplate = template
+
+	// Detect the operating system of the template to pick the bootstrap
+	osInfo := upcloud.DetectTemplateOS(template)
+	log.Infof("Using template %s (%s %s)", template.Title, osInfo.Family, osInfo.Version)
+	if !upcloud.IsBootstrapSupported(template) {
+		log.Warnf("Template %s is not supported by the workspace bootstrap, the workspace may not work", template.Title)
+	}
+	serverConfig.UserData, err = GetUserData(&cloudinit.BootstrapOptions{
+		MachineID: options.MachineID,
+		Family:    osInfo.Family,
+
+		ProviderVersion: Version,
+		Plan:            options.Plan,
+		Zone:            options.Zone,
+		CreatedAt:       time.Now(),
+
+		DockerInstall: dockerInstall,
+		DockerVersion: options.DockerVersion,
+		Docker:        dockerDaemon,
+
+		SwapSize: swapSize,
+		Sysctl:   sysctl,
+	}, extraUserData)
+	if err != nil {
+		return errors.Wrap(err, "build user data")
 	}
 
 	// Create the server
@@ -72,30 +146,90 @@ func (cmd *CreateCmd) Run(ctx context.Context, options *options.Options, log log
 	return nil
 }
 
-func GetCloudInitScript(machineID string) string {
-	return `#!/bin/bash
-set -e
-
-# Create devpod user
-useradd -m -s /bin/bash devpod || true
-usermod -aG docker devpod 2>/dev/null || true
-usermod -aG sudo devpod
-echo "devpod ALL=(ALL) NOPASSWD:ALL" > /etc/sudoers.d/devpod
-
-# Setup SSH directory
-mkdir -p /home/devpod/.ssh
-chmod 700 /home/devpod/.ssh
-chown -R devpod:devpod /home/devpod
-
-# Install Docker if not present
-if ! command -v docker &> /dev/null; then
-    curl -fsSL https://get.docker.com | sh
-    systemctl enable docker
-    systemctl start docker
-fi
-
-# Ensure required directories exist
-mkdir -p /opt/devpod
-chown -R devpod:devpod /opt/devpod
-`
+// GetUserData returns the cloud-init user data bootstrapping a workspace,
+// merged with optional user-supplied user data
+func GetUserData(opts *cloudinit.BootstrapOptions, extra *cloudinit.Part) (string, error) {
+	bootstrap, err := cloudinit.Bootstrap(opts)
+	if err != nil {
+		return "", err
+	}
+
+	userData, err := cloudinit.Merge(bootstrap, extra)
+	if err != nil {
+		return "", err
+	}
+
+	return string(userData), nil
+}
+
+// newDockerDaemon builds the Docker daemon configuration from the options
+func newDockerDaemon(options *options.Options) (*cloudinit.DockerDaemon, error) {
+	daemon := &cloudinit.DockerDaemon{
+		RegistryMirrors:    options.DockerRegistryMirrors,
+		InsecureRegistries: options.DockerInsecureRegistries,
+		LogDriver:          options.DockerLogDriver,
+		DataRoot:           options.DockerDataRoot,
+	}
+
+	if options.DockerLogMaxSize != "" || options.DockerLogMaxFile != "" {
+		daemon.LogOpts = map[string]string{}
+		if options.DockerLogMaxSize != "" {
+			daemon.LogOpts["max-size"] = options.DockerLogMaxSize
+		}
+		if options.DockerLogMaxFile != "" {
+			daemon.LogOpts["max-file"] = options.DockerLogMaxFile
+		}
+	}
+
+	for _, value := range options.DockerAddressPools {
+		pool, err := cloudinit.ParseAddressPool(value)
+		if err != nil {
+			return nil, err
+		}
+		daemon.DefaultAddressPools = append(daemon.DefaultAddressPools, pool)
+	}
+
+	if err := daemon.Validate(); err != nil {
+		return nil, err
+	}
+
+	return daemon, nil
+}
+
+// recommendedSwap sizes the swap file from the plan's RAM. Plans missing from
+// the plan configuration get no swap.
+func recommendedSwap(options *options.Options, log log.Logger) int {
+	plans, err := config.LoadServerPlans()
+	if err != nil {
+		log.Debugf("Not sizing swap: %v", err)
+		return 0
+	}
+
+	planID, err := upcloud.MapPlanName(options.Plan)
+	if err != nil {
+		log.Debugf("Not sizing swap: %v", err)
+		return 0
+	}
+	plan, _, err := plans.GetPlanByID(planID)
+	if err != nil {
+		log.Debugf("Not sizing swap: %v", err)
+		return 0
+	}
+
+	storage, err := upcloud.ParseStorageSize(options.Storage)
+	if err != nil {
+		log.Debugf("Not sizing swap: %v", err)
+		return 0
+	}
+
+	return plan.RecommendedSwap(storage)
+}
+
+// currentOwner returns the local user name recorded as the workspace owner
+func currentOwner() string {
+	current, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return current.Username
 }
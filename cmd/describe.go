@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// DescribeCmd holds the describe command flags
+type DescribeCmd struct {
+	Format string
+}
+
+// NewDescribeCmd defines the describe command
+func NewDescribeCmd() *cobra.Command {
+	cmd := &DescribeCmd{}
+	describeCmd := &cobra.Command{
+		Use:   "describe",
+		Short: "Show details of an instance",
+		Long: `Show details of the workspace server, including its plan, zone, public IP
+and attached storages with their size, tier and encryption state.`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnv(false)
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options, log.Default)
+		},
+	}
+
+	describeCmd.Flags().StringVarP(&cmd.Format, "format", "f", "table", "Output format (table, json, yaml)")
+
+	return describeCmd
+}
+
+// Run runs the command logic
+func (cmd *DescribeCmd) Run(ctx context.Context, options *options.Options, log log.Logger) error {
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+
+	info, err := client.Describe(ctx, options.MachineID)
+	if err != nil {
+		return errors.Wrap(err, "describe server")
+	}
+
+	switch cmd.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(info)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		defer func() {
+			_ = encoder.Close()
+		}()
+		return encoder.Encode(info)
+	default:
+		cmd.outputTable(info)
+		return nil
+	}
+}
+
+// outputTable prints server details in a human readable form
+func (cmd *DescribeCmd) outputTable(info *upcloud.ServerInfo) {
+	fmt.Printf("Machine ID: %s\n", info.MachineID)
+	fmt.Printf("UUID:       %s\n", info.UUID)
+	fmt.Printf("Hostname:   %s\n", info.Hostname)
+	fmt.Printf("Status:     %s\n", info.Status)
+	fmt.Printf("Plan:       %s\n", info.Plan)
+	fmt.Printf("Zone:       %s\n", info.Zone)
+	fmt.Printf("Public IP:  %s\n", info.IP)
+	fmt.Println()
+
+	fmt.Println("Storages:")
+	for _, storage := range info.Storages {
+		encryption := "unencrypted"
+		if storage.Encrypted {
+			encryption = "encrypted"
+		}
+		fmt.Printf("  %-20s %5d GB  %-8s %-11s %s\n", storage.Title, storage.Size, storage.Tier, encryption, storage.UUID)
+	}
+}
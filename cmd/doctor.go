@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	devpodssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+)
+
+// DoctorCmd holds the doctor command flags
+type DoctorCmd struct {
+	MachineFolder string
+	Format        string
+}
+
+// NewDoctorCmd defines the doctor command
+func NewDoctorCmd() *cobra.Command {
+	cmd := &DoctorCmd{}
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the environment for common problems",
+		Long: `Run a series of checks against UpCloud and the local environment and report
+each as pass, warn or fail with a hint on how to fix it.
+
+The checks cover the API connection, the account's API permissions, the zone,
+the plan's availability in the zone, the account's resource limits against
+current usage plus a new workspace, the image or template, the DevPod SSH key
+in the machine folder and the local clock.
+
+The configuration is read from the same UPCLOUD_* options as create. The SSH
+key check uses MACHINE_FOLDER, or --machine-folder, and is skipped without it.
+The command exits with an error if any check fails.`,
+		Example: `  # Check the current configuration
+  devpod-provider-upcloud doctor
+
+  # Check a workspace's SSH key as well
+  devpod-provider-upcloud doctor --machine-folder ~/.devpod/contexts/default/machines/my-machine`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnvInit()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options)
+		},
+	}
+
+	doctorCmd.Flags().StringVar(&cmd.MachineFolder, "machine-folder", "", "DevPod machine folder to check the SSH key in (default MACHINE_FOLDER)")
+	doctorCmd.Flags().StringVarP(&cmd.Format, "format", "f", "table", "Output format (table, json, yaml)")
+
+	return doctorCmd
+}
+
+// Run runs the command logic
+func (cmd *DoctorCmd) Run(ctx context.Context, options *options.Options) error {
+	if cmd.MachineFolder == "" {
+		cmd.MachineFolder = os.Getenv("MACHINE_FOLDER")
+	}
+
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+	diagnostics := client.Diagnose(ctx, &upcloud.ServerConfig{
+		Zone:     options.Zone,
+		Plan:     options.Plan,
+		Storage:  options.Storage,
+		Image:    options.Image,
+		Template: options.Template,
+	})
+	diagnostics = append(diagnostics, diagnoseSSHKey(cmd.MachineFolder))
+
+	var err error
+	switch cmd.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diagnostics)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		err = encoder.Encode(diagnostics)
+		_ = encoder.Close()
+	default:
+		outputDiagnostics(diagnostics)
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Status == upcloud.DiagnosticFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// diagnoseSSHKey checks the DevPod SSH key pair in a machine folder
+func diagnoseSSHKey(machineFolder string) upcloud.Diagnostic {
+	diagnostic := upcloud.Diagnostic{Name: "SSH key"}
+	if machineFolder == "" {
+		diagnostic.Status = upcloud.DiagnosticSkip
+		diagnostic.Message = "skipped, no machine folder given"
+		diagnostic.Hint = "pass --machine-folder or set MACHINE_FOLDER to check a workspace's key"
+		return diagnostic
+	}
+
+	privateKeyPath := filepath.Join(machineFolder, devpodssh.DevPodSSHPrivateKeyFile)
+	publicKeyPath := filepath.Join(machineFolder, devpodssh.DevPodSSHPublicKeyFile)
+
+	privateKey, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		diagnostic.Status = upcloud.DiagnosticFail
+		diagnostic.Message = fmt.Sprintf("cannot read %s: %v", privateKeyPath, err)
+		diagnostic.Hint = "DevPod creates the key when the machine is created; recreate the machine if the folder was removed"
+		return diagnostic
+	}
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		diagnostic.Status = upcloud.DiagnosticFail
+		diagnostic.Message = fmt.Sprintf("invalid private key %s: %v", privateKeyPath, err)
+		diagnostic.Hint = "recreate the machine to get a new key"
+		return diagnostic
+	}
+
+	publicKey, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		diagnostic.Status = upcloud.DiagnosticFail
+		diagnostic.Message = fmt.Sprintf("cannot read %s: %v", publicKeyPath, err)
+		diagnostic.Hint = "recreate the machine to get a new key"
+		return diagnostic
+	}
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+	if err != nil || ssh.FingerprintSHA256(parsed) != ssh.FingerprintSHA256(signer.PublicKey()) {
+		diagnostic.Status = upcloud.DiagnosticFail
+		diagnostic.Message = fmt.Sprintf("%s does not match the private key", publicKeyPath)
+		diagnostic.Hint = "recreate the machine to get a new key"
+		return diagnostic
+	}
+
+	diagnostic.Status = upcloud.DiagnosticPass
+	diagnostic.Message = fmt.Sprintf("key pair %s found", ssh.FingerprintSHA256(parsed))
+
+	// SSH clients refuse keys readable by others
+	if info, err := os.Stat(privateKeyPath); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		diagnostic.Status = upcloud.DiagnosticWarn
+		diagnostic.Message = fmt.Sprintf("%s is accessible by other users (mode %04o)", privateKeyPath, info.Mode().Perm())
+		diagnostic.Hint = "chmod 600 " + privateKeyPath
+	}
+	return diagnostic
+}
+
+// outputDiagnostics prints the diagnostics as a report
+func outputDiagnostics(diagnostics []upcloud.Diagnostic) {
+	fmt.Println("UpCloud Provider Diagnostics")
+	fmt.Println("============================")
+	fmt.Println()
+
+	symbols := map[upcloud.DiagnosticStatus]string{
+		upcloud.DiagnosticPass: "✓",
+		upcloud.DiagnosticWarn: "!",
+		upcloud.DiagnosticFail: "✗",
+		upcloud.DiagnosticSkip: "-",
+	}
+
+	counts := map[upcloud.DiagnosticStatus]int{}
+	for _, diagnostic := range diagnostics {
+		counts[diagnostic.Status]++
+		fmt.Printf("%s %-16s %s\n", symbols[diagnostic.Status], diagnostic.Name, diagnostic.Message)
+		if diagnostic.Hint != "" && diagnostic.Status != upcloud.DiagnosticPass {
+			fmt.Printf("  %-16s 💡 %s\n", "", diagnostic.Hint)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%d passed, %d warnings, %d failed, %d skipped\n",
+		counts[upcloud.DiagnosticPass], counts[upcloud.DiagnosticWarn], counts[upcloud.DiagnosticFail], counts[upcloud.DiagnosticSkip])
+}
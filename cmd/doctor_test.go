@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	devpodssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+func TestDiagnoseSSHKey(t *testing.T) {
+	publicKey, privateKey, err := generateBuilderKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublicKey, _, err := generateBuilderKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		privateKey []byte
+		publicKey  string
+		mode       os.FileMode
+		want       upcloud.DiagnosticStatus
+	}{
+		{"Valid key pair", privateKey, publicKey, 0o600, upcloud.DiagnosticPass},
+		{"Readable by others", privateKey, publicKey, 0o644, upcloud.DiagnosticWarn},
+		{"Mismatched public key", privateKey, otherPublicKey, 0o600, upcloud.DiagnosticFail},
+		{"Invalid private key", []byte("not a key"), publicKey, 0o600, upcloud.DiagnosticFail},
+		{"Missing keys", nil, "", 0, upcloud.DiagnosticFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.privateKey != nil {
+				path := filepath.Join(dir, devpodssh.DevPodSSHPrivateKeyFile)
+				if err := os.WriteFile(path, tt.privateKey, tt.mode); err != nil {
+					t.Fatal(err)
+				}
+				// WriteFile is subject to the umask
+				if err := os.Chmod(path, tt.mode); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, devpodssh.DevPodSSHPublicKeyFile), []byte(tt.publicKey), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := diagnoseSSHKey(dir)
+			if got.Status != tt.want {
+				t.Errorf("diagnoseSSHKey() status = %s (%s), want %s", got.Status, got.Message, tt.want)
+			}
+		})
+	}
+
+	if got := diagnoseSSHKey(""); got.Status != upcloud.DiagnosticSkip {
+		t.Errorf("diagnoseSSHKey(\"\") status = %s, want %s", got.Status, upcloud.DiagnosticSkip)
+	}
+}
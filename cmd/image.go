@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	devpodssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/cloudinit"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ImageBuildCmd holds the image build command flags
+type ImageBuildCmd struct {
+	Name    string
+	Zone    string
+	Plan    string
+	Storage string
+	Image   string
+	Script  string
+	Timeout time.Duration
+}
+
+// NewImageCmd defines the image command group
+func NewImageCmd() *cobra.Command {
+	imageCmd := &cobra.Command{
+		Use:   "image",
+		Short: "Manage workspace images",
+	}
+
+	imageCmd.AddCommand(NewImageBuildCmd())
+	return imageCmd
+}
+
+// NewImageBuildCmd defines the image build command
+func NewImageBuildCmd() *cobra.Command {
+	cmd := &ImageBuildCmd{}
+	buildCmd := &cobra.Command{
+		Use:   "build",
+		Short: "Build a private template with the workspace bootstrap preinstalled",
+		Long: `Build a private UpCloud template with the workspace bootstrap already applied.
+
+A temporary builder server is created from the selected image, the provider's
+bootstrap and an optional provisioning script are run on it, and its disk is
+turned into a private template. Set the printed UUID as UPCLOUD_TEMPLATE to
+skip the bootstrap installation on every workspace create.
+
+The builder server is always deleted afterwards, including on failure.`,
+		Example: `  # Build a template from the default image
+  devpod-provider-upcloud image build
+
+  # Build a Debian template with extra tools installed
+  devpod-provider-upcloud image build --image "Debian 12 (Bookworm)" --script ./provision.sh`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnvInit()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options, log.Default)
+		},
+	}
+
+	buildCmd.Flags().StringVarP(&cmd.Name, "name", "n", "", "Template title (default devpod-<image>-<date>)")
+	buildCmd.Flags().StringVar(&cmd.Zone, "zone", "", "Zone to build the template in (default UPCLOUD_ZONE)")
+	buildCmd.Flags().StringVar(&cmd.Plan, "plan", "", "Plan of the builder server (default UPCLOUD_PLAN)")
+	buildCmd.Flags().StringVar(&cmd.Storage, "storage", "", "Template disk size in GB (default UPCLOUD_STORAGE)")
+	buildCmd.Flags().StringVar(&cmd.Image, "image", "", "Base operating system image (default UPCLOUD_IMAGE)")
+	buildCmd.Flags().StringVar(&cmd.Script, "script", "", "Path to an extra provisioning script run as root after the bootstrap")
+	buildCmd.Flags().DurationVar(&cmd.Timeout, "timeout", 30*time.Minute, "Maximum time for the whole build")
+
+	return buildCmd
+}
+
+// Run runs the command logic
+func (cmd *ImageBuildCmd) Run(ctx context.Context, options *options.Options, log log.Logger) error {
+	cmd.applyDefaults(options)
+
+	var script []byte
+	if cmd.Script != "" {
+		var err error
+		script, err = os.ReadFile(cmd.Script)
+		if err != nil {
+			return errors.Wrap(err, "read provisioning script")
+		}
+	}
+
+	// Check for test mode
+	if options.Username == "test" && options.Password == "test" {
+		log.Infof("Test mode: Simulating image build of %s", cmd.Name)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cmd.Timeout)
+	defer cancel()
+
+	// The builder gets a throwaway key so no workspace key ends up in the template
+	publicKey, privateKey, err := generateBuilderKey()
+	if err != nil {
+		return errors.Wrap(err, "generate builder key")
+	}
+
+	builderID := fmt.Sprintf("devpod-image-builder-%d", time.Now().Unix())
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+
+	userData, err := GetUserData(&cloudinit.BootstrapOptions{
+		MachineID: builderID,
+		Family:    upcloud.DetectOS(cmd.Image).Family,
+
+		ProviderVersion: Version,
+		Plan:            cmd.Plan,
+		Zone:            cmd.Zone,
+		CreatedAt:       time.Now(),
+	}, nil)
+	if err != nil {
+		return errors.Wrap(err, "build user data")
+	}
+
+	// Clean up from here on, so a create failing after the server exists
+	// does not leave it behind
+	defer func() {
+		log.Infof("Deleting builder server %s...", builderID)
+		// Use a fresh context so cleanup still runs after a timeout
+		if err := client.DeleteWithStorages(context.Background(), builderID); err != nil {
+			log.Warnf("Failed to delete builder server %s and its disk, delete them manually: %v", builderID, err)
+		}
+	}()
+
+	log.Infof("Creating builder server %s from %s...", builderID, cmd.Image)
+	err = client.Create(ctx, &upcloud.ServerConfig{
+		Hostname: builderID,
+		Zone:     cmd.Zone,
+		Plan:     cmd.Plan,
+		Storage:  cmd.Storage,
+		Image:    cmd.Image,
+		SSHKey:   publicKey,
+		UserData: userData,
+	})
+	if err != nil {
+		return errors.Wrap(err, "create builder server")
+	}
+
+	err = cmd.provision(ctx, client, builderID, privateKey, script, log)
+	if err != nil {
+		return err
+	}
+
+	// Record the OS so creates from the template pick the right bootstrap
+	labels := map[string]string{}
+	if osInfo := upcloud.DetectOS(cmd.Image); osInfo.Family != upcloud.OSFamilyUnknown {
+		labels[upcloud.LabelOSFamily] = string(osInfo.Family)
+		labels[upcloud.LabelOSVersion] = osInfo.Version
+	}
+
+	log.Infof("Creating template %s...", cmd.Name)
+	templateUUID, err := client.CreateTemplate(ctx, builderID, cmd.Name, labels)
+	if err != nil {
+		return errors.Wrap(err, "create template")
+	}
+
+	log.Infof("Successfully built template %s. Use it with UPCLOUD_TEMPLATE=%s", cmd.Name, templateUUID)
+	fmt.Println(templateUUID)
+	return nil
+}
+
+// applyDefaults fills unset flags from the provider options
+func (cmd *ImageBuildCmd) applyDefaults(options *options.Options) {
+	if cmd.Zone == "" {
+		cmd.Zone = options.Zone
+	}
+	if cmd.Plan == "" {
+		cmd.Plan = options.Plan
+	}
+	if cmd.Storage == "" {
+		cmd.Storage = options.Storage
+	}
+	if cmd.Image == "" {
+		cmd.Image = options.Image
+	}
+	if cmd.Name == "" {
+		slug := strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(cmd.Image), "-"), "-")
+		cmd.Name = fmt.Sprintf("devpod-%s-%s", slug, time.Now().Format("20060102"))
+	}
+}
+
+// provision waits for the bootstrap, runs the extra script and prepares the disk for templating
+func (cmd *ImageBuildCmd) provision(ctx context.Context, client *upcloud.Client, builderID string, privateKey []byte, script []byte, log log.Logger) error {
+	serverIP, err := client.GetServerIP(ctx, builderID)
+	if err != nil {
+		return errors.Wrap(err, "get server ip")
+	}
+
+	log.Infof("Waiting for SSH on %s...", serverIP)
+	sshClient, err := waitForSSH(ctx, serverIP, privateKey)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sshClient.Close()
+	}()
+
+	log.Info("Waiting for bootstrap to finish...")
+	// cloud-init exits with 2 for recoverable errors, which still leave a usable system
+	err = devpodssh.Run(ctx, sshClient, "cloud-init status --wait || [ $? -eq 2 ]", nil, os.Stderr, os.Stderr, nil)
+	if err != nil {
+		return errors.Wrap(err, "bootstrap")
+	}
+	state, logTail, err := getBootstrapState(ctx, sshClient)
+	if err != nil {
+		return errors.Wrap(err, "check bootstrap")
+	}
+	if state == cloudinit.BootstrapFailed {
+		return fmt.Errorf("bootstrap failed, last lines of %s:\n%s", cloudinit.BootstrapLogPath, logTail)
+	}
+
+	if len(script) > 0 {
+		log.Infof("Running provisioning script %s...", cmd.Script)
+		err = devpodssh.Run(ctx, sshClient, "bash -s", bytes.NewReader(script), os.Stderr, os.Stderr, nil)
+		if err != nil {
+			return errors.Wrap(err, "provisioning script")
+		}
+	}
+
+	log.Info("Cleaning up builder state...")
+	err = devpodssh.Run(ctx, sshClient, GetImageCleanupScript(), nil, os.Stderr, os.Stderr, nil)
+	if err != nil {
+		return errors.Wrap(err, "clean up builder")
+	}
+
+	return nil
+}
+
+// GetImageCleanupScript returns a script that removes instance-specific state
+// so that servers cloned from the template boot as fresh instances
+func GetImageCleanupScript() string {
+	return `set -e
+
+# Let cloud-init run again on servers created from the template
+cloud-init clean --logs
+
+# Let the bootstrap run again and report its own progress
+rm -rf ` + cloudinit.StateDir + ` ` + cloudinit.BootstrapLogPath + ` ` + path.Dir(cloudinit.MachineInfoPath) + `
+
+# Remove the builder's credentials and identity
+rm -f /root/.ssh/authorized_keys
+rm -f /etc/ssh/ssh_host_*
+truncate -s 0 /etc/machine-id
+
+# Drop package caches and shell history
+if command -v apt-get >/dev/null 2>&1; then
+    apt-get clean
+else
+    dnf clean all
+fi
+rm -f /root/.bash_history
+sync
+`
+}
+
+// waitForSSH retries connecting to a freshly created server until SSH is up
+func waitForSSH(ctx context.Context, serverIP string, privateKey []byte) (*ssh.Client, error) {
+	for {
+		sshClient, err := devpodssh.NewSSHClient(upcloud.DefaultSSHUser, serverIP+":22", privateKey)
+		if err == nil {
+			return sshClient, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(err, "wait for ssh")
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// generateBuilderKey creates an ephemeral ed25519 key pair for the builder server
+func generateBuilderKey() (string, []byte, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return "", nil, err
+	}
+
+	block, err := ssh.MarshalPrivateKey(private, "devpod-image-builder")
+	if err != nil {
+		return "", nil, err
+	}
+
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey))), pem.EncodeToMemory(block), nil
+}
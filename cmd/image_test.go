@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/cloudinit"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+)
+
+func TestGetImageCleanupScript(t *testing.T) {
+	script := GetImageCleanupScript()
+
+	for _, want := range []string{
+		"set -e",
+		"cloud-init clean --logs",
+		cloudinit.StateDir,
+		cloudinit.BootstrapLogPath,
+		filepath.Dir(cloudinit.MachineInfoPath),
+		"/root/.ssh/authorized_keys",
+		"/etc/ssh/ssh_host_*",
+		"truncate -s 0 /etc/machine-id",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("cleanup script does not contain %q", want)
+		}
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	cmd := exec.Command(bash, "-n")
+	cmd.Stdin = strings.NewReader(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("cleanup script has syntax errors: %v\n%s", err, out)
+	}
+}
+
+func TestImageBuildApplyDefaults(t *testing.T) {
+	opts := &options.Options{
+		Zone:    "de-fra1",
+		Plan:    "DEV-2xCPU-4GB",
+		Storage: "50",
+		Image:   "Ubuntu Server 24.04 LTS (Noble Numbat)",
+	}
+
+	cmd := &ImageBuildCmd{Plan: "2xCPU-4GB"}
+	cmd.applyDefaults(opts)
+
+	if cmd.Zone != "de-fra1" || cmd.Storage != "50" || cmd.Image != opts.Image {
+		t.Errorf("applyDefaults() = %+v, want unset flags from the options", cmd)
+	}
+	if cmd.Plan != "2xCPU-4GB" {
+		t.Errorf("Plan = %s, want the flag kept", cmd.Plan)
+	}
+	want := "devpod-ubuntu-server-24-04-lts-noble-numbat-" + time.Now().Format("20060102")
+	if cmd.Name != want {
+		t.Errorf("Name = %s, want %s", cmd.Name, want)
+	}
+}
+
+func TestImageBuildMissingScript(t *testing.T) {
+	cmd := &ImageBuildCmd{Script: filepath.Join(t.TempDir(), "missing.sh")}
+	opts := &options.Options{Username: "test", Password: "test", Image: "Ubuntu Server 24.04 LTS (Noble Numbat)"}
+
+	err := cmd.Run(context.Background(), opts, log.Discard)
+	if err == nil || !strings.Contains(err.Error(), "read provisioning script") {
+		t.Errorf("Run() error = %v, want the script read error before anything is created", err)
+	}
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// ImagesCmd holds the images command flags
+type ImagesCmd struct {
+	Family  string
+	Private bool
+	Format  string
+}
+
+// imageEntry is a template as shown by the images command
+type imageEntry struct {
+	Title              string `json:"title" yaml:"title"`
+	Family             string `json:"family" yaml:"family"`
+	Version            string `json:"version" yaml:"version"`
+	UUID               string `json:"uuid" yaml:"uuid"`
+	Access             string `json:"access" yaml:"access"`
+	Zone               string `json:"zone,omitempty" yaml:"zone,omitempty"`
+	BootstrapSupported bool   `json:"bootstrap_supported" yaml:"bootstrap_supported"`
+}
+
+// NewImagesCmd defines the images command
+func NewImagesCmd() *cobra.Command {
+	cmd := &ImagesCmd{}
+
+	imagesCmd := &cobra.Command{
+		Use:   "images",
+		Short: "List available OS images",
+		Long: `List UpCloud OS templates usable as UPCLOUD_IMAGE or UPCLOUD_TEMPLATE.
+
+Public templates can be selected by title with UPCLOUD_IMAGE. Private templates,
+such as those created with 'image build', are selected by UUID with
+UPCLOUD_TEMPLATE. Templates the workspace bootstrap cannot provision are marked
+as unsupported.`,
+		Example: `  # List all images
+  devpod-provider-upcloud images
+
+  # Show only Debian-family images
+  devpod-provider-upcloud images --family debian
+
+  # Show only the account's private templates
+  devpod-provider-upcloud images --private
+
+  # Output as JSON
+  devpod-provider-upcloud images --format json`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnvInit()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options)
+		},
+	}
+
+	imagesCmd.Flags().StringVar(&cmd.Family, "family", "", "Filter by OS family (ubuntu, debian, rhel, windows)")
+	imagesCmd.Flags().BoolVar(&cmd.Private, "private", false, "Show only the account's private templates")
+	imagesCmd.Flags().StringVarP(&cmd.Format, "format", "f", "table", "Output format (table, json, yaml)")
+
+	return imagesCmd
+}
+
+// Run executes the images command
+func (cmd *ImagesCmd) Run(ctx context.Context, options *options.Options) error {
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+
+	var templates []upcloud.TemplateInfo
+	if !cmd.Private {
+		public, err := client.GetPublicTemplates(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list public templates: %w", err)
+		}
+		templates = append(templates, public...)
+	}
+
+	private, err := client.GetPrivateTemplates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list private templates: %w", err)
+	}
+	templates = append(templates, private...)
+
+	entries := cmd.buildEntries(templates)
+
+	switch cmd.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		defer func() {
+			_ = encoder.Close()
+		}()
+		return encoder.Encode(entries)
+	default:
+		cmd.outputTable(entries)
+		return nil
+	}
+}
+
+// buildEntries filters templates and sorts them by access, family and title
+func (cmd *ImagesCmd) buildEntries(templates []upcloud.TemplateInfo) []imageEntry {
+	entries := []imageEntry{}
+	for i := range templates {
+		template := &templates[i]
+		osInfo := upcloud.DetectTemplateOS(template)
+		if cmd.Family != "" && string(osInfo.Family) != cmd.Family {
+			continue
+		}
+
+		entries = append(entries, imageEntry{
+			Title:              template.Title,
+			Family:             string(osInfo.Family),
+			Version:            osInfo.Version,
+			UUID:               template.UUID,
+			Access:             template.Access,
+			Zone:               template.Zone,
+			BootstrapSupported: upcloud.IsBootstrapSupported(template),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Access != entries[j].Access {
+			return entries[i].Access > entries[j].Access // public first
+		}
+		if entries[i].Family != entries[j].Family {
+			return entries[i].Family < entries[j].Family
+		}
+		return entries[i].Title < entries[j].Title
+	})
+
+	return entries
+}
+
+// outputTable outputs images in table format
+func (cmd *ImagesCmd) outputTable(entries []imageEntry) {
+	fmt.Println("UpCloud OS Images")
+	fmt.Println("=================")
+	fmt.Println()
+
+	if len(entries) == 0 {
+		fmt.Println("No images found")
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.BootstrapSupported {
+			fmt.Printf("✓ ")
+		} else {
+			fmt.Printf("  ")
+		}
+
+		fmt.Printf("%-8s %-8s %-38s %s", entry.Family, entry.Version, entry.UUID, entry.Title)
+		if entry.Access == "private" {
+			fmt.Printf(" [PRIVATE %s]", entry.Zone)
+		}
+		fmt.Println()
+	}
+
+	fmt.Println()
+	fmt.Println("✓ = supported by the workspace bootstrap")
+	fmt.Println("💡 Tip: Use a public title with UPCLOUD_IMAGE or any UUID with UPCLOUD_TEMPLATE")
+}
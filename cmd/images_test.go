@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	upcloudapi "github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+func TestImagesBuildEntries(t *testing.T) {
+	templates := []upcloud.TemplateInfo{
+		{UUID: "windows", Title: "Windows Server 2022 Standard", Access: upcloudapi.StorageAccessPublic, TemplateType: upcloudapi.StorageTemplateTypeNative},
+		{UUID: "ubuntu", Title: "Ubuntu Server 24.04 LTS (Noble Numbat)", Access: upcloudapi.StorageAccessPublic, TemplateType: upcloudapi.StorageTemplateTypeCloudInit},
+		{UUID: "private", Title: "devpod-workspace", Access: upcloudapi.StorageAccessPrivate, Zone: "de-fra1", TemplateType: upcloudapi.StorageTemplateTypeCloudInit,
+			Labels: map[string]string{upcloud.LabelOSFamily: "ubuntu", upcloud.LabelOSVersion: "24.04"}},
+		{UUID: "rocky", Title: "Rocky Linux 9", Access: upcloudapi.StorageAccessPublic, TemplateType: upcloudapi.StorageTemplateTypeCloudInit},
+		{UUID: "freebsd", Title: "FreeBSD 14.1", Access: upcloudapi.StorageAccessPublic, TemplateType: upcloudapi.StorageTemplateTypeCloudInit},
+	}
+
+	tests := []struct {
+		name     string
+		family   string
+		wantUUID []string
+	}{
+		{"Public first, then by family and title", "", []string{"rocky", "ubuntu", "freebsd", "windows", "private"}},
+		{"Family filter uses template labels", "ubuntu", []string{"ubuntu", "private"}},
+		{"RHEL-alikes", "rhel", []string{"rocky"}},
+		{"No match", "debian", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := (&ImagesCmd{Family: tt.family}).buildEntries(templates)
+			uuids := []string{}
+			for _, entry := range entries {
+				uuids = append(uuids, entry.UUID)
+			}
+			if !reflect.DeepEqual(uuids, tt.wantUUID) {
+				t.Errorf("buildEntries() = %v, want %v", uuids, tt.wantUUID)
+			}
+		})
+	}
+
+	entries := (&ImagesCmd{}).buildEntries(templates)
+	want := map[string]imageEntry{
+		"ubuntu":  {Title: "Ubuntu Server 24.04 LTS (Noble Numbat)", Family: "ubuntu", Version: "24.04", UUID: "ubuntu", Access: "public", BootstrapSupported: true},
+		"private": {Title: "devpod-workspace", Family: "ubuntu", Version: "24.04", UUID: "private", Access: "private", Zone: "de-fra1", BootstrapSupported: true},
+		"windows": {Title: "Windows Server 2022 Standard", Family: "windows", Version: "2022", UUID: "windows", Access: "public"},
+		"freebsd": {Title: "FreeBSD 14.1", Family: "unknown", Version: "14.1", UUID: "freebsd", Access: "public"},
+	}
+	for _, entry := range entries {
+		if expected, ok := want[entry.UUID]; ok && entry != expected {
+			t.Errorf("entry %s = %+v, want %+v", entry.UUID, entry, expected)
+		}
+	}
+}
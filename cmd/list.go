@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// ListCmd holds the list command flags
+type ListCmd struct {
+	Owner  string
+	Zone   string
+	State  string
+	Format string
+}
+
+// serverEntry is a workspace server as shown by the list command
+type serverEntry struct {
+	MachineID  string   `json:"machine_id" yaml:"machine_id"`
+	State      string   `json:"state" yaml:"state"`
+	Plan       string   `json:"plan" yaml:"plan"`
+	Zone       string   `json:"zone" yaml:"zone"`
+	IP         string   `json:"ip,omitempty" yaml:"ip,omitempty"`
+	Owner      string   `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Uptime     string   `json:"uptime,omitempty" yaml:"uptime,omitempty"`
+	HourlyCost *float32 `json:"hourly_cost_eur,omitempty" yaml:"hourly_cost_eur,omitempty"`
+}
+
+// NewListCmd defines the list command
+func NewListCmd() *cobra.Command {
+	cmd := &ListCmd{}
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all DevPod workspace servers in the account",
+		Long: `List the DevPod workspace servers in the UpCloud account across all zones.
+
+Servers are found by the labels the provider sets when creating them. Servers
+created by older provider versions are found by their devpod- title prefix and
+have no owner or uptime. The hourly cost is taken from the provider's plan
+list; the actual price may differ between zones.`,
+		Example: `  # List all workspaces
+  devpod-provider-upcloud list
+
+  # List your running workspaces in Frankfurt
+  devpod-provider-upcloud list --owner "$USER" --zone de-fra1 --state Running
+
+  # Output as JSON
+  devpod-provider-upcloud list --format json`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnvInit()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options)
+		},
+	}
+
+	listCmd.Flags().StringVar(&cmd.Owner, "owner", "", "Show only workspaces created by this user")
+	listCmd.Flags().StringVar(&cmd.Zone, "zone", "", "Show only workspaces in this zone")
+	listCmd.Flags().StringVar(&cmd.State, "state", "", "Show only workspaces in this state (Running, Stopped, Busy)")
+	listCmd.Flags().StringVarP(&cmd.Format, "format", "f", "table", "Output format (table, json, yaml)")
+
+	return listCmd
+}
+
+// Run runs the command logic
+func (cmd *ListCmd) Run(ctx context.Context, options *options.Options) error {
+	plans, err := config.LoadServerPlans()
+	if err != nil {
+		return errors.Wrap(err, "load plans")
+	}
+
+	if cmd.Zone != "" && !plans.IsValidRegion(cmd.Zone) {
+		return fmt.Errorf("unknown zone %q, expected one of: %s", cmd.Zone, strings.Join(plans.GetRegions(), ", "))
+	}
+	if cmd.State != "" {
+		switch {
+		case strings.EqualFold(cmd.State, upcloud.StatusRunning):
+			cmd.State = upcloud.StatusRunning
+		case strings.EqualFold(cmd.State, upcloud.StatusStopped):
+			cmd.State = upcloud.StatusStopped
+		case strings.EqualFold(cmd.State, upcloud.StatusBusy):
+			cmd.State = upcloud.StatusBusy
+		default:
+			return fmt.Errorf("unknown state %q, expected %s, %s or %s", cmd.State, upcloud.StatusRunning, upcloud.StatusStopped, upcloud.StatusBusy)
+		}
+	}
+
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+
+	servers, err := client.ListManagedServers(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list servers")
+	}
+
+	entries := cmd.buildEntries(servers, plans)
+
+	switch cmd.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		defer func() {
+			_ = encoder.Close()
+		}()
+		return encoder.Encode(entries)
+	default:
+		cmd.outputTable(entries)
+		return nil
+	}
+}
+
+// buildEntries filters servers and adds the uptime and hourly cost
+func (cmd *ListCmd) buildEntries(servers []upcloud.ManagedServer, plans *config.ServerPlans) []serverEntry {
+	entries := []serverEntry{}
+	for i := range servers {
+		server := &servers[i]
+		if cmd.Owner != "" && server.Owner != cmd.Owner {
+			continue
+		}
+		if cmd.Zone != "" && server.Zone != cmd.Zone {
+			continue
+		}
+		if cmd.State != "" && server.Status != cmd.State {
+			continue
+		}
+
+		entry := serverEntry{
+			MachineID: server.MachineID,
+			State:     server.Status,
+			Plan:      server.Plan,
+			Zone:      server.Zone,
+			IP:        server.IP,
+			Owner:     server.Owner,
+		}
+		if uptime := server.Uptime(); uptime > 0 {
+			entry.Uptime = formatUptime(uptime)
+		}
+		if plan, _, err := plans.GetPlanByID(server.Plan); err == nil {
+			entry.HourlyCost = &plan.PriceHourly
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// outputTable outputs servers in table format
+func (cmd *ListCmd) outputTable(entries []serverEntry) {
+	fmt.Println("DevPod Workspaces on UpCloud")
+	fmt.Println("============================")
+	fmt.Println()
+
+	if len(entries) == 0 {
+		fmt.Println("No workspaces found")
+		return
+	}
+
+	fmt.Printf("%-32s %-8s %-22s %-8s %-15s %-12s %-8s %s\n",
+		"MACHINE ID", "STATE", "PLAN", "ZONE", "IP", "OWNER", "UPTIME", "COST/H")
+
+	var total float32
+	for _, entry := range entries {
+		cost := "-"
+		if entry.HourlyCost != nil {
+			cost = fmt.Sprintf("€%.4f", *entry.HourlyCost)
+			if entry.State != upcloud.StatusStopped {
+				total += *entry.HourlyCost
+			}
+		}
+
+		fmt.Printf("%-32s %-8s %-22s %-8s %-15s %-12s %-8s %s\n",
+			entry.MachineID, entry.State, entry.Plan, entry.Zone,
+			orDash(entry.IP), orDash(entry.Owner), orDash(entry.Uptime), cost)
+	}
+
+	fmt.Println()
+	fmt.Printf("%d workspace(s), €%.4f per hour while running\n", len(entries), total)
+}
+
+// formatUptime formats a duration as days, hours and minutes
+func formatUptime(uptime time.Duration) string {
+	days := int(uptime.Hours()) / 24
+	hours := int(uptime.Hours()) % 24
+	minutes := int(uptime.Minutes()) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// orDash returns "-" for empty table cells
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -54,6 +54,7 @@ Developer plans are recommended for most DevPod use cases as they offer the best
 	plansCmd.Flags().StringVarP(&cmd.Category, "category", "c", "", "Filter by category (developer, cloud_native, general_purpose, high_cpu, high_memory)")
 	plansCmd.Flags().StringVarP(&cmd.Format, "format", "f", "table", "Output format (table, json, yaml)")
 
+	plansCmd.AddCommand(NewPlansSyncCmd())
 	return plansCmd
 }
 
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// PlansSyncCmd holds the plans sync command flags
+type PlansSyncCmd struct {
+	File   string
+	Zone   string
+	Output string
+	Format string
+}
+
+// NewPlansSyncCmd defines the plans sync command
+func NewPlansSyncCmd() *cobra.Command {
+	cmd := &PlansSyncCmd{}
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Compare the plan catalog with UpCloud's live plans and prices",
+		Long: `Compare the plan catalog with the plans and prices UpCloud currently offers.
+
+Added, removed and changed plans are reported. Prices are taken from the price
+list of one zone. With --output, an updated catalog is written: specifications
+and prices are updated in place, removed plans are dropped and added plans are
+appended to their category, while descriptions, use cases, selection rules and
+comments are kept. Removed plans still used by the default plan or a selection
+rule are kept, and added plans without a catalog category, such as GPU plans,
+are only reported.`,
+		Example: `  # Show how the embedded catalog differs from UpCloud's plans
+  devpod-provider-upcloud plans sync
+
+  # Update the catalog in the repository
+  devpod-provider-upcloud plans sync --file configs/server-plans.yaml --output configs/server-plans.yaml`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnvInit()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options, log.Default)
+		},
+	}
+
+	syncCmd.Flags().StringVar(&cmd.File, "file", "", "Plans YAML file to compare (default the embedded catalog)")
+	syncCmd.Flags().StringVar(&cmd.Zone, "zone", "", "Zone to take prices from (default UPCLOUD_ZONE)")
+	syncCmd.Flags().StringVarP(&cmd.Output, "output", "o", "", "Write the updated plans YAML to this file")
+	syncCmd.Flags().StringVarP(&cmd.Format, "format", "f", "table", "Output format (table, json, yaml)")
+
+	return syncCmd
+}
+
+// Run runs the command logic
+func (cmd *PlansSyncCmd) Run(ctx context.Context, options *options.Options, log log.Logger) error {
+	data := config.ServerPlansYAML()
+	if cmd.File != "" {
+		var err error
+		data, err = os.ReadFile(cmd.File)
+		if err != nil {
+			return errors.Wrap(err, "read plans")
+		}
+	}
+	plans, err := config.ParseServerPlans(data)
+	if err != nil {
+		return err
+	}
+
+	zone := cmd.Zone
+	if zone == "" {
+		zone = options.Zone
+	}
+
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+	live, err := client.GetLivePlans(ctx, zone)
+	if err != nil {
+		return errors.Wrap(err, "get live plans")
+	}
+
+	diff := plans.Diff(live)
+	switch cmd.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(diff)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		err = encoder.Encode(diff)
+		_ = encoder.Close()
+	default:
+		outputPlanDiff(diff, zone, cmd.Output == "")
+	}
+	if err != nil {
+		return err
+	}
+
+	if cmd.Output == "" {
+		return nil
+	}
+	return cmd.write(data, plans, diff, log)
+}
+
+// write writes the updated catalog, keeping removed plans that are still in use
+func (cmd *PlansSyncCmd) write(data []byte, plans *config.ServerPlans, diff *config.PlanDiff, log log.Logger) error {
+	update := *diff
+	update.Removed = nil
+	for _, change := range diff.Removed {
+		if references := plans.References(change.ID); len(references) > 0 {
+			log.Warnf("Keeping removed plan %s, it is used by %s", change.ID, strings.Join(references, ", "))
+			continue
+		}
+		update.Removed = append(update.Removed, change)
+	}
+	for _, change := range diff.Added {
+		if change.Category == "" {
+			log.Infof("Skipping added plan %s, it belongs to no catalog category", change.ID)
+		}
+	}
+
+	updated, err := config.SyncPlansYAML(data, &update, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(cmd.Output, updated, 0644); err != nil {
+		return errors.Wrap(err, "write plans")
+	}
+
+	log.Infof("Wrote the updated plans to %s", cmd.Output)
+	return nil
+}
+
+// outputPlanDiff outputs the catalog differences in table format
+func outputPlanDiff(diff *config.PlanDiff, zone string, tip bool) {
+	fmt.Println("Plan Catalog Sync")
+	fmt.Println("=================")
+	fmt.Printf("Prices from %s\n\n", zone)
+
+	if diff.IsEmpty() {
+		fmt.Println("✅ The plan catalog matches UpCloud's plans")
+		return
+	}
+
+	if len(diff.Added) > 0 {
+		fmt.Printf("Added (%d):\n", len(diff.Added))
+		for _, change := range diff.Added {
+			plan := change.Plan
+			fmt.Printf("  + %-24s %d CPU, %d GB RAM", plan.ID, plan.CPU, plan.RAM/1024)
+			if plan.Storage > 0 {
+				fmt.Printf(", %d GB Storage", plan.Storage)
+			}
+			if plan.PriceMonthly > 0 {
+				fmt.Printf(" - €%.2f/month", plan.PriceMonthly)
+			}
+			fmt.Printf(" (%s)\n", orDash(change.Category))
+		}
+		fmt.Println()
+	}
+
+	if len(diff.Removed) > 0 {
+		fmt.Printf("Removed (%d):\n", len(diff.Removed))
+		for _, change := range diff.Removed {
+			fmt.Printf("  - %-24s (%s)\n", change.ID, change.Category)
+		}
+		fmt.Println()
+	}
+
+	if len(diff.Changed) > 0 {
+		fmt.Printf("Changed (%d):\n", len(diff.Changed))
+		for _, change := range diff.Changed {
+			fmt.Printf("  ~ %-24s (%s)\n", change.ID, change.Category)
+			for _, field := range change.Fields {
+				fmt.Printf("      %s: %s → %s\n", field.Field, field.Old, field.New)
+			}
+		}
+		fmt.Println()
+	}
+
+	if tip {
+		fmt.Println("💡 Tip: Write the updated catalog with --file configs/server-plans.yaml --output configs/server-plans.yaml")
+	}
+}
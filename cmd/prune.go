@@ -0,0 +1,353 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	devpodconfig "github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// PruneCmd holds the prune command flags
+type PruneCmd struct {
+	OlderThan     time.Duration
+	IncludeLegacy bool
+	IncludeIPs    bool
+	Yes           bool
+}
+
+// orphanKind is the type of resource an orphan is
+type orphanKind string
+
+const (
+	orphanServer  orphanKind = "server"
+	orphanStorage orphanKind = "storage"
+	orphanIP      orphanKind = "ip"
+)
+
+// orphan is a resource prune would delete
+type orphan struct {
+	Kind        orphanKind
+	ID          string
+	Name        string
+	Zone        string
+	Reason      string
+	MonthlyCost float64
+}
+
+// pruneResources are the account resources prune looks for orphans in
+type pruneResources struct {
+	Servers  []upcloud.ManagedServer
+	Storages []upcloud.DetachedStorage
+	IPs      []upcloud.FloatingIP
+	Plans    *config.ServerPlans
+	Prices   map[string]upcloud.ZonePrices
+}
+
+// NewPruneCmd defines the prune command
+func NewPruneCmd() *cobra.Command {
+	cmd := &PruneCmd{}
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete orphaned workspace servers, storages and IPs",
+		Long: `Find DevPod resources that no longer belong to a workspace and delete them.
+
+A server or detached disk labelled by the provider is orphaned when it belongs
+to you but has no machine folder in your DevPod home (DEVPOD_HOME or ~/.devpod),
+for example after a failed create or a machine deleted outside DevPod. With
+--older-than, resources of any owner older than the given age are orphaned
+too. Floating IPs attached to orphaned servers are released with them.
+
+Servers and disks created by older provider versions are unlabelled and only
+recognised by their devpod- or "root" title; include them with --include-legacy.
+
+Floating IPs carry no owner or creation time. Unassigned floating IPs, such as
+those left behind by a server deleted manually, are only included with
+--include-floating-ips; check that none of them is used outside DevPod.
+
+Nothing is deleted without --yes; by default the resources that would be
+deleted are listed with the monthly cost they would reclaim.`,
+		Example: `  # Show what would be deleted
+  devpod-provider-upcloud prune
+
+  # Also consider everything older than 30 days, from any owner
+  devpod-provider-upcloud prune --older-than 720h
+
+  # Delete the orphans
+  devpod-provider-upcloud prune --yes`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnvInit()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options, log.Default)
+		},
+	}
+
+	pruneCmd.Flags().DurationVar(&cmd.OlderThan, "older-than", 0, "Also prune resources of any owner older than this age, e.g. 720h")
+	pruneCmd.Flags().BoolVar(&cmd.IncludeLegacy, "include-legacy", false, "Include unlabelled devpod- servers and detached disks titled \"root\" left by older provider versions")
+	pruneCmd.Flags().BoolVar(&cmd.IncludeIPs, "include-floating-ips", false, "Include floating IPs not assigned to any server")
+	pruneCmd.Flags().BoolVarP(&cmd.Yes, "yes", "y", false, "Delete the orphaned resources instead of listing them")
+
+	return pruneCmd
+}
+
+// Run runs the command logic
+func (cmd *PruneCmd) Run(ctx context.Context, options *options.Options, log log.Logger) error {
+	if cmd.OlderThan < 0 {
+		return fmt.Errorf("--older-than must not be negative")
+	}
+
+	folders, err := machineFolders()
+	if err != nil {
+		return errors.Wrap(err, "read machine folders")
+	}
+	if folders == nil {
+		log.Warn("No DevPod machine folders found, only pruning by --older-than")
+	}
+
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+
+	resources, err := cmd.listResources(ctx, client, log)
+	if err != nil {
+		return err
+	}
+	orphans := cmd.findOrphans(resources, folders, currentOwner())
+
+	var total float64
+	for _, orphan := range orphans {
+		total += orphan.MonthlyCost
+	}
+
+	if len(orphans) == 0 {
+		log.Info("No orphaned resources found")
+		return nil
+	}
+
+	for _, orphan := range orphans {
+		fmt.Printf("- %-8s %-40s %-8s €%7.2f/month  %s\n", orphan.Kind, orphan.Name, orphan.Zone, orphan.MonthlyCost, orphan.Reason)
+	}
+	fmt.Println()
+
+	if !cmd.Yes {
+		fmt.Printf("Dry run: %d resource(s) would be deleted, reclaiming €%.2f per month. Re-run with --yes to delete them.\n", len(orphans), total)
+		return nil
+	}
+
+	var reclaimed float64
+	failed := 0
+	for _, orphan := range orphans {
+		if err := deleteOrphan(ctx, client, orphan); err != nil {
+			log.Warnf("Failed to delete %s %s: %v", orphan.Kind, orphan.Name, err)
+			failed++
+			continue
+		}
+		log.Infof("Deleted %s %s", orphan.Kind, orphan.Name)
+		reclaimed += orphan.MonthlyCost
+	}
+
+	fmt.Printf("Deleted %d resource(s), reclaiming €%.2f per month\n", len(orphans)-failed, reclaimed)
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d resource(s)", failed)
+	}
+	return nil
+}
+
+// listResources fetches the servers, detached storages, floating IPs and
+// prices prune looks at
+func (cmd *PruneCmd) listResources(ctx context.Context, client *upcloud.Client, log log.Logger) (*pruneResources, error) {
+	plans, err := config.LoadServerPlans()
+	if err != nil {
+		return nil, errors.Wrap(err, "load plans")
+	}
+
+	// Costs are informational, so prune still works without the price list
+	prices, err := client.GetZonePrices(ctx)
+	if err != nil {
+		log.Warnf("Could not fetch prices, storage and IP costs are not shown: %v", err)
+		prices = map[string]upcloud.ZonePrices{}
+	}
+
+	servers, err := client.ListManagedServers(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "list servers")
+	}
+	storages, err := client.ListDetachedStorages(ctx, cmd.IncludeLegacy)
+	if err != nil {
+		return nil, errors.Wrap(err, "list storages")
+	}
+	ips, err := client.ListFloatingIPs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "list floating ips")
+	}
+
+	return &pruneResources{
+		Servers:  servers,
+		Storages: storages,
+		IPs:      ips,
+		Plans:    plans,
+		Prices:   prices,
+	}, nil
+}
+
+// findOrphans lists the orphaned servers, their floating IPs and detached storages
+func (cmd *PruneCmd) findOrphans(resources *pruneResources, folders map[string]bool, owner string) []orphan {
+	plans, prices := resources.Plans, resources.Prices
+
+	orphans := []orphan{}
+	for i := range resources.Servers {
+		server := &resources.Servers[i]
+		// Unlabelled servers only match by title and may not be the provider's
+		if !server.Labelled && !cmd.IncludeLegacy {
+			continue
+		}
+		reason := cmd.orphanReason(server.MachineID, server.Owner, server.CreatedAt, folders, owner)
+		if reason == "" {
+			continue
+		}
+
+		cost := prices[server.Zone].MonthlyPlanCost(server.Plan)
+		if plan, _, err := plans.GetPlanByID(server.Plan); err == nil {
+			cost = float64(plan.PriceMonthly)
+		}
+		orphans = append(orphans, orphan{
+			Kind:        orphanServer,
+			ID:          server.UUID,
+			Name:        server.MachineID,
+			Zone:        server.Zone,
+			Reason:      reason,
+			MonthlyCost: cost,
+		})
+
+		// Floating IPs outlive their server, release them after it is deleted
+		for _, ip := range resources.IPs {
+			if ip.ServerUUID != server.UUID {
+				continue
+			}
+			orphans = append(orphans, orphan{
+				Kind:        orphanIP,
+				ID:          ip.Address,
+				Name:        ip.Address,
+				Zone:        ip.Zone,
+				Reason:      "floating IP of " + server.MachineID,
+				MonthlyCost: prices[ip.Zone].MonthlyIPv4Cost(),
+			})
+		}
+	}
+
+	for _, storage := range resources.Storages {
+		reason := "detached, created before storages were labelled"
+		if storage.Labelled {
+			createdAt := storage.CreatedAt
+			reason = cmd.orphanReason(storage.MachineID, storage.Owner, &createdAt, folders, owner)
+			if reason == "" {
+				continue
+			}
+			reason = "detached, " + reason
+		} else if cmd.OlderThan > 0 && time.Since(storage.CreatedAt) < cmd.OlderThan {
+			continue
+		}
+
+		name := storage.Title
+		if storage.MachineID != "" {
+			name = storage.MachineID + "/" + storage.Title
+		}
+		orphans = append(orphans, orphan{
+			Kind:        orphanStorage,
+			ID:          storage.UUID,
+			Name:        fmt.Sprintf("%s (%d GB)", name, storage.Size),
+			Zone:        storage.Zone,
+			Reason:      reason,
+			MonthlyCost: prices[storage.Zone].MonthlyStorageCost(storage.Tier, storage.Size),
+		})
+	}
+
+	if cmd.IncludeIPs {
+		for _, ip := range resources.IPs {
+			if ip.ServerUUID != "" {
+				continue
+			}
+			orphans = append(orphans, orphan{
+				Kind:        orphanIP,
+				ID:          ip.Address,
+				Name:        ip.Address,
+				Zone:        ip.Zone,
+				Reason:      "floating IP not assigned to a server",
+				MonthlyCost: prices[ip.Zone].MonthlyIPv4Cost(),
+			})
+		}
+	}
+
+	return orphans
+}
+
+// orphanReason returns why a resource is orphaned, or "" if it is not. Only
+// the current user's resources are checked against the local machine folders,
+// since other users' machines live on their own hosts.
+func (cmd *PruneCmd) orphanReason(machineID, resourceOwner string, createdAt *time.Time, folders map[string]bool, owner string) string {
+	if folders != nil && owner != "" && resourceOwner == owner && machineID != "" && !folders[machineID] {
+		return "no machine folder"
+	}
+	if cmd.OlderThan > 0 && createdAt != nil && time.Since(*createdAt) > cmd.OlderThan {
+		return fmt.Sprintf("older than %s", cmd.OlderThan)
+	}
+	return ""
+}
+
+// deleteOrphan deletes a single orphaned resource
+func deleteOrphan(ctx context.Context, client *upcloud.Client, orphan orphan) error {
+	switch orphan.Kind {
+	case orphanServer:
+		return client.DeleteServerByUUID(ctx, orphan.ID)
+	case orphanStorage:
+		return client.DeleteStorage(ctx, orphan.ID)
+	case orphanIP:
+		return client.ReleaseIP(ctx, orphan.ID)
+	default:
+		return fmt.Errorf("unknown resource kind %s", orphan.Kind)
+	}
+}
+
+// machineFolders returns the IDs of the machines in every DevPod context, or
+// nil if DevPod has no contexts on this host
+func machineFolders() (map[string]bool, error) {
+	configDir, err := devpodconfig.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	contexts, err := os.ReadDir(filepath.Join(configDir, "contexts"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	folders := map[string]bool{}
+	for _, devpodContext := range contexts {
+		machines, err := os.ReadDir(filepath.Join(configDir, "contexts", devpodContext.Name(), "machines"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, machine := range machines {
+			if machine.IsDir() {
+				folders[machine.Name()] = true
+			}
+		}
+	}
+
+	return folders, nil
+}
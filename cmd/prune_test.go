@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+func TestPruneOrphanReason(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+	folders := map[string]bool{"devpod-kept": true}
+
+	tests := []struct {
+		name      string
+		olderThan time.Duration
+		machineID string
+		owner     string
+		createdAt *time.Time
+		folders   map[string]bool
+		want      string
+	}{
+		{"Own machine with folder", 0, "devpod-kept", "alice", &old, folders, ""},
+		{"Own machine without folder", 0, "devpod-gone", "alice", &recent, folders, "no machine folder"},
+		{"Other owner without folder", 0, "devpod-gone", "bob", &old, folders, ""},
+		{"Unowned without folder", 0, "devpod-gone", "", &old, folders, ""},
+		{"No DevPod home", 0, "devpod-gone", "alice", &old, nil, ""},
+		{"Other owner older than threshold", 24 * time.Hour, "devpod-gone", "bob", &old, folders, "older than 24h0m0s"},
+		{"Other owner newer than threshold", 24 * time.Hour, "devpod-gone", "bob", &recent, folders, ""},
+		{"Own machine with folder older than threshold", 24 * time.Hour, "devpod-kept", "alice", &old, folders, "older than 24h0m0s"},
+		{"Unknown age", 24 * time.Hour, "devpod-gone", "bob", nil, folders, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &PruneCmd{OlderThan: tt.olderThan}
+			got := cmd.orphanReason(tt.machineID, tt.owner, tt.createdAt, tt.folders, "alice")
+			if got != tt.want {
+				t.Errorf("orphanReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneFindOrphans(t *testing.T) {
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	resources := &pruneResources{
+		Servers: []upcloud.ManagedServer{
+			{
+				ServerInfo: upcloud.ServerInfo{UUID: "labelled", MachineID: "devpod-labelled", Plan: "DEV-2xCPU-4GB"},
+				Labelled:   true,
+				CreatedAt:  &old,
+			},
+			{
+				ServerInfo: upcloud.ServerInfo{UUID: "unlabelled", MachineID: "devpod-unlabelled", Plan: "DEV-2xCPU-4GB"},
+				CreatedAt:  &old,
+			},
+		},
+		IPs: []upcloud.FloatingIP{
+			{Address: "192.0.2.1", ServerUUID: "labelled"},
+			{Address: "192.0.2.2", ServerUUID: "unlabelled"},
+			{Address: "192.0.2.3"},
+		},
+		Plans:  &config.ServerPlans{},
+		Prices: map[string]upcloud.ZonePrices{},
+	}
+
+	tests := []struct {
+		name string
+		cmd  PruneCmd
+		want []string
+	}{
+		{"Unlabelled servers skipped by default", PruneCmd{OlderThan: 720 * time.Hour}, []string{"devpod-labelled", "192.0.2.1"}},
+		{"Unlabelled servers with --include-legacy", PruneCmd{OlderThan: 720 * time.Hour, IncludeLegacy: true},
+			[]string{"devpod-labelled", "192.0.2.1", "devpod-unlabelled", "192.0.2.2"}},
+		{"Unassigned floating IPs with --include-floating-ips", PruneCmd{IncludeIPs: true}, []string{"192.0.2.3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, orphan := range tt.cmd.findOrphans(resources, nil, "alice") {
+				got = append(got, orphan.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findOrphans() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
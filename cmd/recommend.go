@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/project"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// RecommendCmd holds the recommend command flags
+type RecommendCmd struct {
+	Language  string
+	Framework string
+	Workload  string
+	Dir       string
+	Format    string
+}
+
+// recommendation is the output of the recommend command
+type recommendation struct {
+	Plan         string          `json:"plan" yaml:"plan"`
+	DisplayName  string          `json:"display_name" yaml:"display_name"`
+	CPU          int             `json:"cpu" yaml:"cpu"`
+	RAM          int             `json:"ram_mb" yaml:"ram_mb"`
+	Storage      int             `json:"storage_gb" yaml:"storage_gb"`
+	PriceHourly  float32         `json:"price_hourly_eur" yaml:"price_hourly_eur"`
+	PriceMonthly float32         `json:"price_monthly_eur" yaml:"price_monthly_eur"`
+	Stacks       []project.Stack `json:"detected,omitempty" yaml:"detected,omitempty"`
+	Reasons      []string        `json:"reasons" yaml:"reasons"`
+}
+
+// NewRecommendCmd defines the recommend command
+func NewRecommendCmd() *cobra.Command {
+	cmd := &RecommendCmd{}
+	recommendCmd := &cobra.Command{
+		Use:   "recommend",
+		Short: "Recommend a server plan for a project",
+		Long: `Recommend a server plan from the provider's selection rules.
+
+Without --language, --framework or --workload the project directory is scanned
+for devcontainer.json, package.json, go.mod, Cargo.toml, pom.xml, Gradle
+builds, requirements.txt, pyproject.toml, .csproj and CMakeLists.txt files, up
+to two directories deep. When several stacks are found, as in a monorepo, the
+largest of their recommended plans is picked.
+
+Workload rules take precedence over framework rules, and framework rules over
+language rules.`,
+		Example: `  # Scan the current directory
+  devpod-provider-upcloud recommend
+
+  # Scan another project
+  devpod-provider-upcloud recommend --dir ~/src/my-app
+
+  # Recommend for an explicit stack
+  devpod-provider-upcloud recommend --language python --workload data_science`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return cmd.Run()
+		},
+	}
+
+	recommendCmd.Flags().StringVar(&cmd.Language, "language", "", "Programming language, e.g. go, python, typescript")
+	recommendCmd.Flags().StringVar(&cmd.Framework, "framework", "", "Framework, e.g. react, django, spring")
+	recommendCmd.Flags().StringVar(&cmd.Workload, "workload", "", "Workload, e.g. frontend, database, ml_development")
+	recommendCmd.Flags().StringVarP(&cmd.Dir, "dir", "d", ".", "Project directory to scan when no stack is given")
+	recommendCmd.Flags().StringVarP(&cmd.Format, "format", "f", "table", "Output format (table, json, yaml)")
+
+	return recommendCmd
+}
+
+// Run runs the command logic
+func (cmd *RecommendCmd) Run() error {
+	plans, err := config.LoadServerPlans()
+	if err != nil {
+		return errors.Wrap(err, "load plans")
+	}
+
+	var stacks []project.Stack
+	explicit := cmd.Language != "" || cmd.Framework != "" || cmd.Workload != ""
+	if explicit {
+		stacks = []project.Stack{{Language: cmd.Language, Framework: cmd.Framework, Workload: cmd.Workload}}
+	} else {
+		stacks, err = project.Detect(cmd.Dir)
+		if err != nil {
+			return errors.Wrap(err, "scan project")
+		}
+	}
+
+	result, err := recommendPlan(plans, stacks, explicit)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		defer func() {
+			_ = encoder.Close()
+		}()
+		return encoder.Encode(result)
+	default:
+		outputRecommendation(result)
+		return nil
+	}
+}
+
+// recommendPlan picks the largest plan recommended for any of the stacks and
+// explains how it was chosen
+func recommendPlan(plans *config.ServerPlans, stacks []project.Stack, explicit bool) (*recommendation, error) {
+	result := &recommendation{}
+	if !explicit {
+		result.Stacks = stacks
+	}
+
+	if len(stacks) == 0 {
+		result.Reasons = append(result.Reasons, "No supported project files found")
+		stacks = []project.Stack{{}}
+	}
+
+	var best *config.ServerPlan
+	for _, stack := range stacks {
+		planID, rule := plans.RecommendPlan(stack.Language, stack.Framework, stack.Workload)
+		plan, _, err := plans.GetPlanByID(planID)
+		if err != nil {
+			return nil, fmt.Errorf("selection rules recommend unknown plan %s: %w", planID, err)
+		}
+
+		switch {
+		case stack.Source != "":
+			result.Reasons = append(result.Reasons, fmt.Sprintf("Detected %s in %s: %s by %s", stack, stack.Source, planID, rule))
+		case stack.String() != "":
+			result.Reasons = append(result.Reasons, fmt.Sprintf("%s: %s by %s", capitalize(stack.String()), planID, rule))
+		default:
+			result.Reasons = append(result.Reasons, fmt.Sprintf("%s by %s", planID, rule))
+		}
+
+		if best == nil || largerPlan(plan, best) {
+			best = plan
+		}
+	}
+
+	if len(stacks) > 1 {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("Picked %s, the largest plan needed by the detected stacks", best.ID))
+	}
+
+	result.Plan = best.ID
+	result.DisplayName = best.DisplayName
+	result.CPU = best.CPU
+	result.RAM = best.RAM
+	result.Storage = best.Storage
+	result.PriceHourly = best.PriceHourly
+	result.PriceMonthly = best.PriceMonthly
+	return result, nil
+}
+
+// largerPlan compares plans by memory, then CPU, then price
+func largerPlan(a, b *config.ServerPlan) bool {
+	if a.RAM != b.RAM {
+		return a.RAM > b.RAM
+	}
+	if a.CPU != b.CPU {
+		return a.CPU > b.CPU
+	}
+	return a.PriceMonthly > b.PriceMonthly
+}
+
+// capitalize upper-cases the first letter of a sentence
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// outputRecommendation outputs the recommendation in table format
+func outputRecommendation(result *recommendation) {
+	fmt.Println("Recommended Plan")
+	fmt.Println("================")
+	fmt.Println()
+
+	fmt.Printf("%s (%s)\n", result.Plan, result.DisplayName)
+	fmt.Printf("  %d CPU, %d GB RAM", result.CPU, result.RAM/1024)
+	if result.Storage > 0 {
+		fmt.Printf(", %d GB storage", result.Storage)
+	}
+	fmt.Printf(" - €%.2f/month, €%.4f/hour\n", result.PriceMonthly, result.PriceHourly)
+	fmt.Println()
+
+	fmt.Println("Reasoning:")
+	for _, reason := range result.Reasons {
+		fmt.Printf("  - %s\n", reason)
+	}
+
+	fmt.Println()
+	fmt.Printf("💡 Tip: Use this plan with UPCLOUD_PLAN=%s\n", result.Plan)
+}
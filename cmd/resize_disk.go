@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+
+	devpodssh "github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ResizeDiskCmd holds the resize-disk command flags
+type ResizeDiskCmd struct {
+	Size string
+}
+
+// NewResizeDiskCmd defines the resize-disk command
+func NewResizeDiskCmd() *cobra.Command {
+	cmd := &ResizeDiskCmd{}
+	resizeDiskCmd := &cobra.Command{
+		Use:   "resize-disk",
+		Short: "Grow the root disk of an instance",
+		Long: `Grow the root storage of an existing workspace without recreating it.
+
+The storage is resized through the UpCloud API, stopping and restarting the
+server if UpCloud requires it, and the root partition and filesystem are then
+grown over SSH. Storages can only grow, never shrink.`,
+		Example: `  # Grow the workspace disk to 100 GB
+  devpod-provider-upcloud resize-disk --size 100`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnv(false)
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options, log.Default)
+		},
+	}
+
+	resizeDiskCmd.Flags().StringVarP(&cmd.Size, "size", "s", "", "New disk size in GB")
+	_ = resizeDiskCmd.MarkFlagRequired("size")
+
+	return resizeDiskCmd
+}
+
+// Run runs the command logic
+func (cmd *ResizeDiskCmd) Run(ctx context.Context, options *options.Options, log log.Logger) error {
+	size, err := upcloud.ParseStorageSize(cmd.Size)
+	if err != nil {
+		return err
+	}
+
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+
+	log.Infof("Resizing root storage of %s to %d GB...", options.MachineID, size)
+	err = client.ResizeStorage(ctx, options.MachineID, size)
+	if err != nil {
+		return errors.Wrap(err, "resize storage")
+	}
+
+	// Check for test mode
+	if options.Username == "test" && options.Password == "test" {
+		log.Info("Test mode: Simulating filesystem resize")
+		return nil
+	}
+
+	log.Infof("Growing partition and filesystem on %s...", options.MachineID)
+	sshClient, err := newSSHClient(ctx, options)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = sshClient.Close()
+	}()
+
+	stdout := &bytes.Buffer{}
+	err = devpodssh.Run(ctx, sshClient, GetGrowFilesystemScript(), nil, stdout, os.Stderr, nil)
+	if err != nil {
+		return errors.Wrap(err, "grow filesystem")
+	}
+	log.Debugf("Filesystem resize output: %s", stdout.String())
+
+	log.Infof("Successfully resized disk of %s to %d GB", options.MachineID, size)
+	return nil
+}
+
+// GetGrowFilesystemScript returns a script that grows the root partition and
+// filesystem to fill the underlying disk
+func GetGrowFilesystemScript() string {
+	return `set -e
+
+ROOT_SOURCE=$(findmnt -n -o SOURCE /)
+ROOT_FSTYPE=$(findmnt -n -o FSTYPE /)
+ROOT_NAME=$(basename "$ROOT_SOURCE")
+
+# Grow the partition if the root filesystem lives on one
+if [ -f "/sys/class/block/$ROOT_NAME/partition" ]; then
+    PART_NUM=$(cat "/sys/class/block/$ROOT_NAME/partition")
+    DISK="/dev/$(lsblk -n -o PKNAME "$ROOT_SOURCE")"
+
+    if ! command -v growpart >/dev/null 2>&1; then
+        if command -v apt-get >/dev/null 2>&1; then
+            apt-get install -y cloud-guest-utils
+        else
+            dnf install -y cloud-utils-growpart
+        fi
+    fi
+
+    # growpart exits with 1 when there is nothing to grow
+    growpart "$DISK" "$PART_NUM" || [ $? -eq 1 ]
+fi
+
+case "$ROOT_FSTYPE" in
+    ext2|ext3|ext4)
+        resize2fs "$ROOT_SOURCE"
+        ;;
+    xfs)
+        xfs_growfs /
+        ;;
+    *)
+        echo "Unsupported root filesystem: $ROOT_FSTYPE" >&2
+        exit 1
+        ;;
+esac
+
+df -h /
+`
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetGrowFilesystemScript(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	tests := []struct {
+		fstype    string
+		wantCall  string
+		wantError bool
+	}{
+		{"ext4", "resize2fs /dev/devpodtest1", false},
+		{"xfs", "xfs_growfs /", false},
+		{"btrfs", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fstype, func(t *testing.T) {
+			// Stub the disk tools; the root device has no partition in /sys,
+			// so only the filesystem is grown
+			bin := t.TempDir()
+			calls := filepath.Join(bin, "calls")
+			stubs := map[string]string{
+				"findmnt":    `case "$*" in *SOURCE*) echo /dev/devpodtest1 ;; *FSTYPE*) echo ` + tt.fstype + ` ;; esac`,
+				"resize2fs":  `echo "resize2fs $*" >> ` + calls,
+				"xfs_growfs": `echo "xfs_growfs $*" >> ` + calls,
+				"df":         `true`,
+			}
+			for name, body := range stubs {
+				if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+body+"\n"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmd := exec.Command(bash, "-c", GetGrowFilesystemScript())
+			cmd.Env = append(os.Environ(), "PATH="+bin+":"+os.Getenv("PATH"))
+			out, err := cmd.CombinedOutput()
+			if tt.wantError {
+				if err == nil || !strings.Contains(string(out), "Unsupported root filesystem: btrfs") {
+					t.Errorf("script succeeded or gave the wrong error: %v\n%s", err, out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("script failed: %v\n%s", err, out)
+			}
+
+			got, _ := os.ReadFile(calls)
+			if strings.TrimSpace(string(got)) != tt.wantCall {
+				t.Errorf("calls = %q, want %q", got, tt.wantCall)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// ResizePlanCmd holds the resize-plan command flags
+type ResizePlanCmd struct {
+	Plan string
+}
+
+// NewResizePlanCmd defines the resize-plan command
+func NewResizePlanCmd() *cobra.Command {
+	cmd := &ResizePlanCmd{}
+	resizePlanCmd := &cobra.Command{
+		Use:   "resize-plan",
+		Short: "Move an instance to a different server plan",
+		Long: `Move an existing workspace to a different server plan without recreating it.
+
+The server is stopped, switched to the new plan and started again. If it fails
+to start on the new plan, the previous plan is restored.`,
+		Example: `  # Give the workspace more memory
+  devpod-provider-upcloud resize-plan --plan DEV-2xCPU-8GB`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnv(false)
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options, log.Default)
+		},
+	}
+
+	resizePlanCmd.Flags().StringVarP(&cmd.Plan, "plan", "p", "", "Target server plan (run 'plans' to list available plans)")
+	_ = resizePlanCmd.MarkFlagRequired("plan")
+
+	return resizePlanCmd
+}
+
+// Run runs the command logic
+func (cmd *ResizePlanCmd) Run(ctx context.Context, options *options.Options, log log.Logger) error {
+	plans, err := config.LoadServerPlans()
+	if err != nil {
+		return fmt.Errorf("failed to load server plans: %w", err)
+	}
+
+	// Validate the target plan
+	plan, err := upcloud.MapPlanName(cmd.Plan)
+	if err != nil {
+		return err
+	}
+	if target, _, err := plans.GetPlanByID(plan); err == nil {
+		log.Infof("Target plan %s: %d CPU, %d GB RAM - €%.2f/month", target.ID, target.CPU, target.RAM/1024, target.PriceMonthly)
+	}
+
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+
+	log.Infof("Changing plan of %s to %s...", options.MachineID, plan)
+	err = client.ChangePlan(ctx, options.MachineID, plan)
+	if err != nil {
+		return errors.Wrap(err, "change plan")
+	}
+
+	log.Infof("Successfully changed plan of %s to %s", options.MachineID, plan)
+	return nil
+}
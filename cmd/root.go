@@ -9,11 +9,15 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
+// Version is the provider version, set by main
+var Version = "dev"
+
 // NewRootCmd returns a new root command
 func NewRootCmd() *cobra.Command {
 	ucCmd := &cobra.Command{
 		Use:           "devpod-provider-upcloud",
 		Short:         "UpCloud provider commands",
+		Version:       Version,
 		SilenceErrors: true,
 		SilenceUsage:  true,
 
@@ -55,11 +59,23 @@ func BuildRoot() *cobra.Command {
 
 	rootCmd.AddCommand(NewCreateCmd())
 	rootCmd.AddCommand(NewStatusCmd())
+	rootCmd.AddCommand(NewDescribeCmd())
 	rootCmd.AddCommand(NewDeleteCmd())
 	rootCmd.AddCommand(NewStartCmd())
 	rootCmd.AddCommand(NewStopCmd())
 	rootCmd.AddCommand(NewCommandCmd())
 	rootCmd.AddCommand(NewInitCmd())
 	rootCmd.AddCommand(NewPlansCmd())
+	rootCmd.AddCommand(NewImagesCmd())
+	rootCmd.AddCommand(NewResizeDiskCmd())
+	rootCmd.AddCommand(NewResizePlanCmd())
+	rootCmd.AddCommand(NewImageCmd())
+	rootCmd.AddCommand(NewListCmd())
+	rootCmd.AddCommand(NewPruneCmd())
+	rootCmd.AddCommand(NewDoctorCmd())
+	rootCmd.AddCommand(NewCostCmd())
+	rootCmd.AddCommand(NewRecommendCmd())
+	rootCmd.AddCommand(NewZonesCmd())
+	rootCmd.AddCommand(NewTunnelCmd())
 	return rootCmd
 }
@@ -1,15 +1,29 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net"
+	"time"
 
+	devpodssh "github.com/loft-sh/devpod/pkg/ssh"
 	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/cloudinit"
 	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
 	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
 )
 
+// sshProbeTimeout limits how long status waits for the SSH port
+const sshProbeTimeout = 5 * time.Second
+
+// sshBootTimeout is how long after a start an unreachable SSH port counts as
+// the server still booting
+const sshBootTimeout = 10 * time.Minute
+
 // StatusCmd holds the cmd flags
 type StatusCmd struct{}
 
@@ -42,7 +56,87 @@ func (cmd *StatusCmd) Run(ctx context.Context, options *options.Options, log log
 		return nil
 	}
 
+	// A running server is only ready once the bootstrap has finished
+	if status == upcloud.StatusRunning && !(options.Username == "test" && options.Password == "test") {
+		state, logTail := cmd.bootstrapState(ctx, client, options, log)
+		switch state {
+		case cloudinit.BootstrapRunning:
+			status = upcloud.StatusBusy
+		case cloudinit.BootstrapFailed:
+			// DevPod treats a failing status as a provider error, which would
+			// also block stopping and deleting the workspace
+			log.Errorf("Workspace bootstrap failed, last lines of %s:\n%s", cloudinit.BootstrapLogPath, logTail)
+		}
+	}
+
 	// Print status for DevPod to consume
 	fmt.Println(status)
 	return nil
 }
+
+// bootstrapState checks the bootstrap markers over SSH. A server whose SSH
+// port is not reachable shortly after it started is still booting, so it
+// counts as running. Other problems, such as a wrong key or a filtered port,
+// would keep the workspace busy forever, so they are reported as a warning and
+// the state as unknown; DevPod's own SSH connection then fails with the actual
+// error.
+func (cmd *StatusCmd) bootstrapState(ctx context.Context, client *upcloud.Client, options *options.Options, log log.Logger) (cloudinit.BootstrapState, string) {
+	server, err := client.GetManagedServer(ctx, options.MachineID)
+	if err != nil {
+		log.Warnf("Could not check the workspace bootstrap: %v", err)
+		return cloudinit.BootstrapUnknown, ""
+	}
+	if server.IP == "" {
+		log.Warnf("Could not check the workspace bootstrap: server %s has no public IPv4 address", options.MachineID)
+		return cloudinit.BootstrapUnknown, ""
+	}
+	serverIP := server.IP
+
+	// Connecting to a booting server can hang, so probe the port first
+	conn, err := net.DialTimeout("tcp", serverIP+":22", sshProbeTimeout)
+	if err != nil {
+		if isBooting(server) {
+			log.Debugf("Bootstrap check: SSH not reachable yet: %v", err)
+			return cloudinit.BootstrapRunning, ""
+		}
+		log.Warnf("Could not check the workspace bootstrap, SSH is not reachable %s after the start: %v", sshBootTimeout, err)
+		return cloudinit.BootstrapUnknown, ""
+	}
+	_ = conn.Close()
+
+	sshClient, err := dialSSH(serverIP, options.MachineFolder)
+	if err != nil {
+		log.Warnf("Could not check the workspace bootstrap: %v", err)
+		return cloudinit.BootstrapUnknown, ""
+	}
+	defer func() {
+		_ = sshClient.Close()
+	}()
+
+	state, logTail, err := getBootstrapState(ctx, sshClient)
+	if err != nil {
+		log.Warnf("Could not check the workspace bootstrap: %v", err)
+		return cloudinit.BootstrapUnknown, ""
+	}
+
+	return state, logTail
+}
+
+// getBootstrapState reads the bootstrap markers on a server
+func getBootstrapState(ctx context.Context, sshClient *ssh.Client) (cloudinit.BootstrapState, string, error) {
+	var stdout bytes.Buffer
+	err := devpodssh.Run(ctx, sshClient, cloudinit.BootstrapStatusScript(), nil, &stdout, io.Discard, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, logTail := cloudinit.ParseBootstrapStatus(stdout.String())
+	return state, logTail, nil
+}
+
+// isBooting checks if a server started recently enough for its SSH port to
+// still be closed. Servers without a known start time are not waited for.
+func isBooting(server *upcloud.ManagedServer) bool {
+	uptime := server.Uptime()
+	return uptime > 0 && uptime < sshBootTimeout
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+func TestIsBooting(t *testing.T) {
+	started := func(ago time.Duration) *time.Time {
+		startedAt := time.Now().Add(-ago)
+		return &startedAt
+	}
+
+	tests := []struct {
+		name      string
+		status    string
+		startedAt *time.Time
+		want      bool
+	}{
+		{"Just started", upcloud.StatusRunning, started(time.Minute), true},
+		{"Started before the boot timeout", upcloud.StatusRunning, started(sshBootTimeout - time.Minute), true},
+		{"Started after the boot timeout", upcloud.StatusRunning, started(sshBootTimeout + time.Minute), false},
+		{"Unknown start time", upcloud.StatusRunning, nil, false},
+		{"Not running", upcloud.StatusStopped, started(time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &upcloud.ManagedServer{
+				ServerInfo: upcloud.ServerInfo{Status: tt.status},
+				StartedAt:  tt.startedAt,
+			}
+			if got := isBooting(server); got != tt.want {
+				t.Errorf("isBooting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
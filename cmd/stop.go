@@ -2,13 +2,23 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"os/exec"
 
 	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/cloudinit"
 	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
 	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+// How detectGuest inspects the host, replaced in tests
+var (
+	readMachineInfo     = cloudinit.ReadMachineInfo
+	getInstanceMetadata = upcloud.GetInstanceMetadata
+)
+
 // StopCmd holds the cmd flags
 type StopCmd struct{}
 
@@ -18,7 +28,18 @@ func NewStopCmd() *cobra.Command {
 	stopCmd := &cobra.Command{
 		Use:   "stop",
 		Short: "Stop an instance",
+		Long: `Stop an instance.
+
+Run on a workspace itself, as the agent does when the inactivity timeout
+expires, stop powers off the guest OS instead of calling the UpCloud API, so
+no account credentials are needed inside the workspace.`,
 		RunE: func(_ *cobra.Command, args []string) error {
+			// The agent's inactivity shutdown runs stop on the workspace itself,
+			// where no API credentials are available
+			if machine, ok := detectGuest(context.Background(), log.Default); ok {
+				return cmd.RunInGuest(machine, log.Default)
+			}
+
 			options, err := options.FromEnv(false)
 			if err != nil {
 				return err
@@ -44,3 +65,44 @@ func (cmd *StopCmd) Run(ctx context.Context, options *options.Options, log log.L
 	log.Infof("Successfully stopped server %s", options.MachineID)
 	return nil
 }
+
+// RunInGuest powers off the workspace from inside the guest. UpCloud sees the
+// server as stopped once the OS has shut down.
+func (cmd *StopCmd) RunInGuest(machine *cloudinit.MachineInfo, log log.Logger) error {
+	log.Infof("Powering off workspace %s from inside the guest...", machine.MachineID)
+
+	args := []string{"systemctl", "poweroff"}
+	if os.Geteuid() != 0 {
+		args = append([]string{"sudo", "-n"}, args...)
+	}
+
+	poweroff := exec.Command(args[0], args[1:]...)
+	poweroff.Stdout = os.Stderr
+	poweroff.Stderr = os.Stderr
+	if err := poweroff.Run(); err != nil {
+		return errors.Wrap(err, "power off")
+	}
+	return nil
+}
+
+// detectGuest checks if stop runs on the workspace it is asked to stop: the
+// bootstrap's machine.json must exist, match MACHINE_ID if one is set, and the
+// UpCloud metadata service must answer. Anything else is a host-side stop.
+func detectGuest(ctx context.Context, log log.Logger) (*cloudinit.MachineInfo, bool) {
+	machine, err := readMachineInfo()
+	if err != nil {
+		return nil, false
+	}
+
+	if machineID := os.Getenv("MACHINE_ID"); machineID != "" && machineID != machine.MachineID {
+		log.Debugf("Stopping %s from workspace %s, using the API", machineID, machine.MachineID)
+		return nil, false
+	}
+
+	if _, err := getInstanceMetadata(ctx); err != nil {
+		log.Debugf("Not running on an UpCloud server: %v", err)
+		return nil, false
+	}
+
+	return machine, true
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/cloudinit"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+func TestDetectGuest(t *testing.T) {
+	machine := &cloudinit.MachineInfo{MachineID: "devpod-workspace"}
+	onUpCloud := func(context.Context) (*upcloud.InstanceMetadata, error) {
+		return &upcloud.InstanceMetadata{CloudName: "upcloud"}, nil
+	}
+
+	tests := []struct {
+		name        string
+		machineInfo func() (*cloudinit.MachineInfo, error)
+		machineID   string
+		metadata    func(context.Context) (*upcloud.InstanceMetadata, error)
+		want        bool
+		wantQueried bool
+	}{
+		{
+			name:        "Workspace server",
+			machineInfo: func() (*cloudinit.MachineInfo, error) { return machine, nil },
+			machineID:   "devpod-workspace",
+			metadata:    onUpCloud,
+			want:        true,
+			wantQueried: true,
+		},
+		{
+			name:        "Workspace server without MACHINE_ID",
+			machineInfo: func() (*cloudinit.MachineInfo, error) { return machine, nil },
+			metadata:    onUpCloud,
+			want:        true,
+			wantQueried: true,
+		},
+		{
+			name: "Missing machine.json",
+			machineInfo: func() (*cloudinit.MachineInfo, error) {
+				return nil, &fs.PathError{Op: "open", Path: cloudinit.MachineInfoPath, Err: fs.ErrNotExist}
+			},
+			machineID: "devpod-workspace",
+			metadata:  onUpCloud,
+		},
+		{
+			name:        "Stopping another workspace",
+			machineInfo: func() (*cloudinit.MachineInfo, error) { return machine, nil },
+			machineID:   "devpod-other",
+			metadata:    onUpCloud,
+		},
+		{
+			name:        "Not on UpCloud",
+			machineInfo: func() (*cloudinit.MachineInfo, error) { return machine, nil },
+			machineID:   "devpod-workspace",
+			metadata: func(context.Context) (*upcloud.InstanceMetadata, error) {
+				return nil, errors.New(`not running on UpCloud (cloud "aws")`)
+			},
+			wantQueried: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queried := false
+			readMachineInfo = tt.machineInfo
+			getInstanceMetadata = func(ctx context.Context) (*upcloud.InstanceMetadata, error) {
+				queried = true
+				return tt.metadata(ctx)
+			}
+			t.Cleanup(func() {
+				readMachineInfo = cloudinit.ReadMachineInfo
+				getInstanceMetadata = upcloud.GetInstanceMetadata
+			})
+			t.Setenv("MACHINE_ID", tt.machineID)
+
+			got, ok := detectGuest(context.Background(), log.Discard)
+			if ok != tt.want || (ok && got != machine) {
+				t.Errorf("detectGuest() = %v, %v, want %v", got, ok, tt.want)
+			}
+			// The metadata service is only asked once stop is known to target this workspace
+			if queried != tt.wantQueried {
+				t.Errorf("metadata queried = %v, want %v", queried, tt.wantQueried)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	devpodconfig "github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/log"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+// maxReconnectDelay caps the backoff between reconnect attempts
+const maxReconnectDelay = 30 * time.Second
+
+// minTunnelUptime is how long a tunnel has to stay up before a drop starts
+// the backoff over
+const minTunnelUptime = time.Minute
+
+// TunnelCmd holds the tunnel command flags
+type TunnelCmd struct {
+	MachineFolder string
+	Local         []string
+	Remote        []string
+	KeepAlive     time.Duration
+	NoReconnect   bool
+}
+
+// portForward is a port forwarded through the tunnel. Local forwards listen
+// on this host and connect from the server, reverse forwards the other way.
+type portForward struct {
+	Reverse bool
+	Listen  string
+	Target  string
+}
+
+func (f portForward) String() string {
+	if f.Reverse {
+		return fmt.Sprintf("server %s → local %s", f.Listen, f.Target)
+	}
+	return fmt.Sprintf("local %s → server %s", f.Listen, f.Target)
+}
+
+// bindError is a forward that cannot listen on its address, such as a port
+// already in use. Reconnecting does not help, so the tunnel exits.
+type bindError struct {
+	forward portForward
+	err     error
+}
+
+func (e *bindError) Error() string {
+	return fmt.Sprintf("forward %s: listen on %s: %v", e.forward, e.forward.Listen, e.err)
+}
+
+func (e *bindError) Unwrap() error {
+	return e.err
+}
+
+// NewTunnelCmd defines the tunnel command
+func NewTunnelCmd() *cobra.Command {
+	cmd := &TunnelCmd{}
+	tunnelCmd := &cobra.Command{
+		Use:   "tunnel [machine-id]",
+		Short: "Forward ports between this host and a workspace server",
+		Long: `Forward ports between this host and a workspace server over SSH, for services
+running on the server itself rather than in the devcontainer.
+
+The server and key are found the same way as for DevPod's own commands: the
+IP from the UpCloud API and the private key from the machine folder. The
+machine ID defaults to MACHINE_ID, and the machine folder to MACHINE_FOLDER or
+the machine's folder in the DevPod home.
+
+Forwards are written like ssh's -L and -R options: [bind_address:]port:host:hostport,
+or port:hostport and port for forwards to localhost on the other side. The
+tunnel runs until interrupted, sends a keepalive to detect dropped connections
+and reconnects with a backoff. It exits if a port cannot be bound, on either
+side, as reconnecting would not help.`,
+		Example: `  # Reach a service listening on port 8080 of the server
+  devpod-provider-upcloud tunnel devpod-my-workspace -L 8080
+
+  # Forward local port 15432 to a database the server can reach
+  devpod-provider-upcloud tunnel devpod-my-workspace -L 15432:db.internal:5432
+
+  # Let the server reach a service on this host at its port 9000
+  devpod-provider-upcloud tunnel devpod-my-workspace -R 9000:localhost:3000`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnvInit()
+			if err != nil {
+				return err
+			}
+
+			options.MachineID = os.Getenv("MACHINE_ID")
+			if len(args) > 0 {
+				options.MachineID = args[0]
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return cmd.Run(ctx, options, log.Default)
+		},
+	}
+
+	tunnelCmd.Flags().StringVar(&cmd.MachineFolder, "machine-folder", "", "DevPod machine folder with the SSH key (default MACHINE_FOLDER or the DevPod home)")
+	tunnelCmd.Flags().StringArrayVarP(&cmd.Local, "local", "L", nil, "Forward a local port to the server, [bind_address:]port:host:hostport")
+	tunnelCmd.Flags().StringArrayVarP(&cmd.Remote, "remote", "R", nil, "Forward a server port to this host, [bind_address:]port:host:hostport")
+	tunnelCmd.Flags().DurationVar(&cmd.KeepAlive, "keepalive", 30*time.Second, "Interval of keepalive requests, 0 to disable")
+	tunnelCmd.Flags().BoolVar(&cmd.NoReconnect, "no-reconnect", false, "Exit when the connection drops instead of reconnecting")
+
+	return tunnelCmd
+}
+
+// Run runs the command logic
+func (cmd *TunnelCmd) Run(ctx context.Context, options *options.Options, log log.Logger) error {
+	if options.MachineID == "" {
+		return errors.New("no machine ID given, pass it as an argument or set MACHINE_ID")
+	}
+
+	var forwards []portForward
+	for _, spec := range cmd.Local {
+		forward, err := parsePortForward(spec, false)
+		if err != nil {
+			return err
+		}
+		forwards = append(forwards, forward)
+	}
+	for _, spec := range cmd.Remote {
+		forward, err := parsePortForward(spec, true)
+		if err != nil {
+			return err
+		}
+		forwards = append(forwards, forward)
+	}
+	if len(forwards) == 0 {
+		return errors.New("no ports to forward, use --local or --remote")
+	}
+
+	// Check for test mode
+	if options.Username == "test" && options.Password == "test" {
+		for _, forward := range forwards {
+			log.Infof("Test mode: Simulating tunnel %s on %s", forward, options.MachineID)
+		}
+		return nil
+	}
+
+	options.MachineFolder = cmd.MachineFolder
+	if options.MachineFolder == "" {
+		options.MachineFolder = os.Getenv("MACHINE_FOLDER")
+	}
+	if options.MachineFolder == "" {
+		folder, err := findMachineFolder(options.MachineID)
+		if err != nil {
+			return err
+		}
+		options.MachineFolder = folder
+	}
+
+	// Fail early instead of reconnecting forever when a local port is taken
+	for _, forward := range forwards {
+		if forward.Reverse {
+			continue
+		}
+		listener, err := net.Listen("tcp", forward.Listen)
+		if err != nil {
+			return errors.Wrapf(err, "listen on %s", forward.Listen)
+		}
+		_ = listener.Close()
+	}
+
+	var delay time.Duration
+	for {
+		up, err := cmd.runTunnel(ctx, options, forwards, log)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var bindErr *bindError
+		if cmd.NoReconnect || errors.As(err, &bindErr) {
+			return err
+		}
+
+		delay = reconnectDelay(delay, up)
+		log.Warnf("Tunnel to %s dropped: %v. Reconnecting in %s...", options.MachineID, err, delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// reconnectDelay returns the backoff before the next reconnect, doubling the
+// previous one unless the tunnel stayed up long enough to count as working
+func reconnectDelay(previous, up time.Duration) time.Duration {
+	if previous == 0 || up >= minTunnelUptime {
+		return time.Second
+	}
+	return min(previous*2, maxReconnectDelay)
+}
+
+// runTunnel connects to the server and forwards ports until the connection
+// or a forward fails. It returns how long the connection was up.
+func (cmd *TunnelCmd) runTunnel(ctx context.Context, options *options.Options, forwards []portForward, log log.Logger) (time.Duration, error) {
+	sshClient, err := newSSHClient(ctx, options)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = sshClient.Close()
+	}()
+	connectedAt := time.Now()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(forwards)+2)
+	for _, forward := range forwards {
+		log.Infof("Forwarding %s", forward)
+		go func(forward portForward) {
+			errs <- runForward(ctx, sshClient, forward, log)
+		}(forward)
+	}
+	go func() {
+		errs <- sshClient.Wait()
+	}()
+	if cmd.KeepAlive > 0 {
+		go func() {
+			errs <- keepAlive(ctx, sshClient, cmd.KeepAlive)
+		}()
+	}
+
+	err = <-errs
+	if err == nil {
+		err = errors.New("connection closed")
+	}
+	return time.Since(connectedAt), err
+}
+
+// runForward listens for a forward and proxies its connections until the
+// context is done or the listener fails. Listen errors are returned as a
+// bindError, except for a reverse forward on a connection that just closed.
+func runForward(ctx context.Context, sshClient *ssh.Client, forward portForward, log log.Logger) error {
+	listen, dial := net.Listen, sshClient.Dial
+	if forward.Reverse {
+		listen, dial = sshClient.Listen, net.Dial
+	}
+
+	listener, err := listen("tcp", forward.Listen)
+	if err != nil {
+		if forward.Reverse && errors.Is(err, io.EOF) {
+			return err
+		}
+		return &bindError{forward: forward, err: err}
+	}
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrapf(err, "forward %s", forward)
+		}
+		go proxyConn(conn, dial, forward.Target, log)
+	}
+}
+
+// proxyConn copies data between an accepted connection and its target until
+// either side closes
+func proxyConn(conn net.Conn, dial func(network, addr string) (net.Conn, error), target string, log log.Logger) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	targetConn, err := dial("tcp", target)
+	if err != nil {
+		log.Debugf("Could not connect to %s: %v", target, err)
+		return
+	}
+	defer func() {
+		_ = targetConn.Close()
+	}()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(targetConn, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, targetConn)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// keepAlive sends keepalive requests until one fails or is not answered
+// within the interval
+func keepAlive(ctx context.Context, client *ssh.Client, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		reply := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			reply <- err
+		}()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-reply:
+			if err != nil {
+				return errors.Wrap(err, "keepalive")
+			}
+		case <-time.After(interval):
+			return errors.New("keepalive timed out")
+		}
+	}
+}
+
+// parsePortForward parses a forward written like ssh's -L and -R options:
+// [bind_address:]port:host:hostport, port:hostport or port. Omitted hosts
+// are localhost.
+func parsePortForward(spec string, reverse bool) (portForward, error) {
+	parts := strings.Split(spec, ":")
+	bind, port, host, hostPort := "localhost", "", "localhost", ""
+	switch len(parts) {
+	case 1:
+		port, hostPort = parts[0], parts[0]
+	case 2:
+		port, hostPort = parts[0], parts[1]
+	case 3:
+		port, host, hostPort = parts[0], parts[1], parts[2]
+	case 4:
+		bind, port, host, hostPort = parts[0], parts[1], parts[2], parts[3]
+	default:
+		return portForward{}, fmt.Errorf("invalid forward %q, expected [bind_address:]port:host:hostport", spec)
+	}
+
+	for _, p := range []string{port, hostPort} {
+		if n, err := strconv.Atoi(p); err != nil || n < 1 || n > 65535 {
+			return portForward{}, fmt.Errorf("invalid forward %q: %q is not a port", spec, p)
+		}
+	}
+	if bind == "" || host == "" {
+		return portForward{}, fmt.Errorf("invalid forward %q: empty host", spec)
+	}
+
+	return portForward{
+		Reverse: reverse,
+		Listen:  net.JoinHostPort(bind, port),
+		Target:  net.JoinHostPort(host, hostPort),
+	}, nil
+}
+
+// findMachineFolder finds the folder of a machine in the DevPod contexts
+func findMachineFolder(machineID string) (string, error) {
+	configDir, err := devpodconfig.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	folders, err := filepath.Glob(filepath.Join(configDir, "contexts", "*", "machines", machineID))
+	if err != nil {
+		return "", err
+	}
+	if len(folders) == 0 {
+		return "", fmt.Errorf("no machine folder found for %s in %s, pass it with --machine-folder", machineID, configDir)
+	}
+	if len(folders) > 1 {
+		return "", fmt.Errorf("machine %s exists in several DevPod contexts, pass its folder with --machine-folder", machineID)
+	}
+
+	return folders[0], nil
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/loft-sh/log"
+)
+
+func TestParsePortForward(t *testing.T) {
+	tests := []struct {
+		spec    string
+		reverse bool
+		want    portForward
+		wantErr bool
+	}{
+		{"8080", false, portForward{Listen: "localhost:8080", Target: "localhost:8080"}, false},
+		{"8080:3000", false, portForward{Listen: "localhost:8080", Target: "localhost:3000"}, false},
+		{"15432:db.internal:5432", false, portForward{Listen: "localhost:15432", Target: "db.internal:5432"}, false},
+		{"0.0.0.0:8080:localhost:3000", true, portForward{Reverse: true, Listen: "0.0.0.0:8080", Target: "localhost:3000"}, false},
+		{"http", false, portForward{}, true},
+		{"8080:70000", false, portForward{}, true},
+		{"8080::3000", false, portForward{}, true},
+		{"a:b:c:d:e", false, portForward{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parsePortForward(tt.spec, tt.reverse)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePortForward() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parsePortForward() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMachineFolder(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("DEVPOD_HOME", home)
+
+	folder := filepath.Join(home, "contexts", "default", "machines", "devpod-one")
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := findMachineFolder("devpod-one")
+	if err != nil || got != folder {
+		t.Errorf("findMachineFolder() = %q, %v, want %q", got, err, folder)
+	}
+
+	if _, err := findMachineFolder("devpod-missing"); err == nil {
+		t.Error("findMachineFolder() found a missing machine")
+	}
+
+	if err := os.MkdirAll(filepath.Join(home, "contexts", "team", "machines", "devpod-one"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := findMachineFolder("devpod-one"); err == nil {
+		t.Error("findMachineFolder() picked one of several contexts")
+	}
+}
+
+func TestReconnectDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous time.Duration
+		up       time.Duration
+		want     time.Duration
+	}{
+		{"First drop", 0, 0, time.Second},
+		{"Dropped right after connecting", time.Second, time.Second, 2 * time.Second},
+		{"Repeated drops back off", 8 * time.Second, 0, 16 * time.Second},
+		{"Backoff is capped", 20 * time.Second, 0, maxReconnectDelay},
+		{"Working tunnel starts over", 16 * time.Second, minTunnelUptime, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reconnectDelay(tt.previous, tt.up); got != tt.want {
+				t.Errorf("reconnectDelay(%s, %s) = %s, want %s", tt.previous, tt.up, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunForwardPortInUse(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = taken.Close()
+	}()
+
+	forward := portForward{Listen: taken.Addr().String(), Target: "localhost:8080"}
+	err = runForward(context.Background(), nil, forward, log.Discard)
+
+	var bindErr *bindError
+	if !errors.As(err, &bindErr) {
+		t.Errorf("runForward() error = %v, want a bind error", err)
+	}
+}
+
+func TestRunForwardStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- runForward(ctx, nil, portForward{Listen: "127.0.0.1:0", Target: "localhost:8080"}, log.Discard)
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("runForward() error = %v, want nil after cancel", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runForward() did not stop after cancel")
+	}
+}
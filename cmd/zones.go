@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/options"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// ZonesCmd holds the zones command flags
+type ZonesCmd struct {
+	Detailed bool
+	Format   string
+}
+
+// zoneEntry is a zone as shown by the zones command
+type zoneEntry struct {
+	upcloud.ZoneInfo `yaml:",inline"`
+
+	InCatalog      bool     `json:"in_catalog" yaml:"in_catalog"`
+	Encryption     bool     `json:"encryption" yaml:"encryption"`
+	Priced         bool     `json:"priced" yaml:"priced"`
+	AvailablePlans []string `json:"available_plans" yaml:"available_plans"`
+	MissingPlans   []string `json:"missing_plans,omitempty" yaml:"missing_plans,omitempty"`
+}
+
+// zonesReport is the output of the zones command
+type zonesReport struct {
+	Zones         []zoneEntry `json:"zones" yaml:"zones"`
+	CatalogPlans  int         `json:"catalog_plans" yaml:"catalog_plans"`
+	Discrepancies []string    `json:"discrepancies,omitempty" yaml:"discrepancies,omitempty"`
+}
+
+// NewZonesCmd defines the zones command
+func NewZonesCmd() *cobra.Command {
+	cmd := &ZonesCmd{}
+	zonesCmd := &cobra.Command{
+		Use:   "zones",
+		Short: "List UpCloud zones and the plans available in each",
+		Long: `List the zones available to the UpCloud account with their description and
+country, and how many plans of the provider's catalog are sold in each.
+
+Plan availability comes from the zone's price list. Zones UpCloud offers that
+are missing from the provider's embedded region list, and embedded regions
+UpCloud no longer offers, are reported as discrepancies. Private cloud zones
+are listed but not compared.`,
+		Example: `  # List zones
+  devpod-provider-upcloud zones
+
+  # Also list the catalog plans missing from each zone
+  devpod-provider-upcloud zones --detailed
+
+  # Output as JSON
+  devpod-provider-upcloud zones --format json`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			options, err := options.FromEnvInit()
+			if err != nil {
+				return err
+			}
+
+			return cmd.Run(context.Background(), options)
+		},
+	}
+
+	zonesCmd.Flags().BoolVarP(&cmd.Detailed, "detailed", "d", false, "List the catalog plans missing from each zone")
+	zonesCmd.Flags().StringVarP(&cmd.Format, "format", "f", "table", "Output format (table, json, yaml)")
+
+	return zonesCmd
+}
+
+// Run runs the command logic
+func (cmd *ZonesCmd) Run(ctx context.Context, options *options.Options) error {
+	plans, err := config.LoadServerPlans()
+	if err != nil {
+		return errors.Wrap(err, "load plans")
+	}
+
+	client := upcloud.NewUpCloud(options.Username, options.Password)
+
+	zones, err := client.GetZones(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list zones")
+	}
+	prices, err := client.GetZonePrices(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get prices")
+	}
+
+	report := buildZonesReport(zones, prices, plans)
+
+	switch cmd.Format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		defer func() {
+			_ = encoder.Close()
+		}()
+		return encoder.Encode(report)
+	default:
+		cmd.outputTable(report)
+		return nil
+	}
+}
+
+// buildZonesReport checks the catalog plans sold in each zone and compares
+// the public zones with the embedded region list
+func buildZonesReport(zones []upcloud.ZoneInfo, prices map[string]upcloud.ZonePrices, plans *config.ServerPlans) *zonesReport {
+	catalogPlans := catalogPlanIDs(plans)
+	report := &zonesReport{Zones: []zoneEntry{}, CatalogPlans: len(catalogPlans)}
+
+	offered := map[string]bool{}
+	for _, zone := range zones {
+		entry := zoneEntry{
+			ZoneInfo:       zone,
+			InCatalog:      plans.IsValidRegion(zone.ID),
+			Encryption:     plans.SupportsEncryption(zone.ID),
+			AvailablePlans: []string{},
+		}
+
+		zonePrices, priced := prices[zone.ID]
+		entry.Priced = priced
+		for _, plan := range catalogPlans {
+			if priced && zonePrices.HasPlan(plan) {
+				entry.AvailablePlans = append(entry.AvailablePlans, plan)
+			} else if priced {
+				entry.MissingPlans = append(entry.MissingPlans, plan)
+			}
+		}
+
+		if zone.Public {
+			offered[zone.ID] = true
+			if !entry.InCatalog {
+				report.Discrepancies = append(report.Discrepancies,
+					fmt.Sprintf("%s (%s) is offered by UpCloud but missing from the embedded region list", zone.ID, zone.Description))
+			}
+		}
+		report.Zones = append(report.Zones, entry)
+	}
+
+	for _, region := range plans.GetRegions() {
+		if !offered[region] {
+			report.Discrepancies = append(report.Discrepancies,
+				fmt.Sprintf("%s is in the embedded region list but not offered by UpCloud", region))
+		}
+	}
+
+	return report
+}
+
+// catalogPlanIDs returns the IDs of all catalog plans, ordered by category
+func catalogPlanIDs(plans *config.ServerPlans) []string {
+	var categories []string
+	for key := range plans.Categories {
+		categories = append(categories, key)
+	}
+	sort.Strings(categories)
+
+	var ids []string
+	for _, key := range categories {
+		for _, plan := range plans.Categories[key].Plans {
+			ids = append(ids, plan.ID)
+		}
+	}
+	return ids
+}
+
+// outputTable outputs zones in table format
+func (cmd *ZonesCmd) outputTable(report *zonesReport) {
+	fmt.Println("UpCloud Zones")
+	fmt.Println("=============")
+	fmt.Println()
+
+	if len(report.Zones) == 0 {
+		fmt.Println("No zones found")
+		return
+	}
+
+	fmt.Printf("%-10s %-24s %-16s %-8s %s\n", "ZONE", "DESCRIPTION", "COUNTRY", "PLANS", "NOTES")
+	for _, zone := range report.Zones {
+		available := "-"
+		if zone.Priced {
+			available = fmt.Sprintf("%d/%d", len(zone.AvailablePlans), report.CatalogPlans)
+		}
+
+		var notes []string
+		if !zone.Public {
+			notes = append(notes, "private cloud")
+		}
+		if zone.Public && !zone.InCatalog {
+			notes = append(notes, "not in catalog")
+		}
+		if zone.Encryption {
+			notes = append(notes, "encryption")
+		}
+		if !zone.Priced {
+			notes = append(notes, "no price list")
+		}
+
+		fmt.Printf("%-10s %-24s %-16s %-8s %s\n",
+			zone.ID, zone.Description, orDash(zone.Country), available, strings.Join(notes, ", "))
+
+		if cmd.Detailed && len(zone.MissingPlans) > 0 {
+			fmt.Printf("    Missing: %s\n", strings.Join(zone.MissingPlans, ", "))
+		}
+	}
+
+	fmt.Println()
+	if len(report.Discrepancies) == 0 {
+		fmt.Println("✅ The embedded region list matches UpCloud's public zones")
+		return
+	}
+
+	fmt.Println("⚠️  Discrepancies with the embedded region list:")
+	for _, discrepancy := range report.Discrepancies {
+		fmt.Printf("  - %s\n", discrepancy)
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	upcloudapi "github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+func TestBuildZonesReport(t *testing.T) {
+	plans := &config.ServerPlans{
+		Categories: map[string]*config.PlanCategory{
+			"developer":       {Plans: []config.ServerPlan{{ID: "DEV-2xCPU-4GB"}}},
+			"general_purpose": {Plans: []config.ServerPlan{{ID: "2xCPU-4GB"}}},
+		},
+		Metadata: config.PlanMetadata{
+			RegionsAvailable:  []string{"de-fra1", "xx-old1"},
+			EncryptionRegions: []string{"de-fra1"},
+		},
+	}
+	zones := []upcloud.ZoneInfo{
+		{ID: "de-fra1", Description: "Frankfurt #1", Public: true},
+		{ID: "es-mad1", Description: "Madrid #1", Public: true},
+		{ID: "fi-hel1-private", Description: "Private cloud", ParentZone: "fi-hel1"},
+	}
+	prices := map[string]upcloud.ZonePrices{
+		"de-fra1": {"server_plan_DEV-2xCPU-4GB": upcloudapi.Price{Amount: 1, Price: 2.68}},
+		"es-mad1": {},
+	}
+
+	report := buildZonesReport(zones, prices, plans)
+
+	if report.CatalogPlans != 2 {
+		t.Errorf("CatalogPlans = %d, want 2", report.CatalogPlans)
+	}
+	if len(report.Zones) != 3 {
+		t.Fatalf("got %d zones, want 3", len(report.Zones))
+	}
+
+	fra := report.Zones[0]
+	if !fra.InCatalog || !fra.Encryption || !fra.Priced {
+		t.Errorf("de-fra1 = %+v, want in catalog, encrypted and priced", fra)
+	}
+	if !reflect.DeepEqual(fra.AvailablePlans, []string{"DEV-2xCPU-4GB"}) || !reflect.DeepEqual(fra.MissingPlans, []string{"2xCPU-4GB"}) {
+		t.Errorf("de-fra1 plans = %v available, %v missing", fra.AvailablePlans, fra.MissingPlans)
+	}
+
+	private := report.Zones[2]
+	if private.Priced || len(private.AvailablePlans) != 0 || len(private.MissingPlans) != 0 {
+		t.Errorf("private zone = %+v, want no plan availability", private)
+	}
+
+	want := []string{
+		"es-mad1 (Madrid #1) is offered by UpCloud but missing from the embedded region list",
+		"xx-old1 is in the embedded region list but not offered by UpCloud",
+	}
+	if !reflect.DeepEqual(report.Discrepancies, want) {
+		t.Errorf("Discrepancies = %v, want %v", report.Discrepancies, want)
+	}
+}
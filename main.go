@@ -4,7 +4,11 @@ import (
 	"github.com/neuralmux/devpod-provider-upcloud/cmd"
 )
 
+// version is set at build time by goreleaser
+var version = "dev"
+
 func main() {
 	// As of Go 1.20, the random number generator is automatically seeded
+	cmd.Version = version
 	cmd.Execute()
 }
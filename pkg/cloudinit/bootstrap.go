@@ -0,0 +1,206 @@
+package cloudinit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+// Workspace bootstrap constants
+const (
+	// BootstrapUser is the user created for DevPod on every workspace
+	BootstrapUser = "devpod"
+
+	// InstallDockerPath is where the Docker install script is written
+	InstallDockerPath = "/usr/local/lib/devpod/install-docker.sh"
+
+	// BootstrapScriptPath is the script running the bootstrap steps at first boot
+	BootstrapScriptPath = "/usr/local/lib/devpod/bootstrap.sh"
+
+	// BootstrapLogPath receives the output of the bootstrap script
+	BootstrapLogPath = "/var/log/devpod-bootstrap.log"
+
+	// StateDir holds the provider's state on the server
+	StateDir = "/var/lib/devpod"
+
+	// BootstrapDoneMarker and BootstrapFailedMarker are created when the
+	// bootstrap script finishes or fails
+	BootstrapDoneMarker   = StateDir + "/bootstrap.done"
+	BootstrapFailedMarker = StateDir + "/bootstrap.failed"
+
+	// AgentDir is the directory the DevPod agent works in
+	AgentDir = "/opt/devpod"
+)
+
+// BootstrapOptions configures the workspace bootstrap
+type BootstrapOptions struct {
+	MachineID string
+	Family    upcloud.OSFamily
+
+	// Recorded in machine.json and the MOTD
+	ProviderVersion string
+	Plan            string
+	Zone            string
+	CreatedAt       time.Time
+
+	// DockerInstall is where Docker is installed from, the repository by default
+	DockerInstall DockerInstall
+	// DockerVersion pins and verifies the Docker version, any version if empty
+	DockerVersion string
+
+	// Docker is written to /etc/docker/daemon.json before Docker first starts
+	Docker *DockerDaemon
+
+	// SwapSize is the size of the swap file in MB, no swap if zero
+	SwapSize int
+	// Sysctl holds kernel parameters applied at first boot and on every boot after
+	Sysctl map[string]string
+}
+
+// Bootstrap returns the cloud-config that prepares a workspace: the devpod
+// user with passwordless sudo, Docker and the agent directory. The admin
+// group and the Docker installation depend on the OS family; unknown families
+// detect the package manager at boot time.
+func Bootstrap(opts *BootstrapOptions) (*Config, error) {
+	user := User{
+		Name:       BootstrapUser,
+		Shell:      "/bin/bash",
+		Sudo:       "ALL=(ALL) NOPASSWD:ALL",
+		LockPasswd: true,
+	}
+	switch opts.Family {
+	case upcloud.OSFamilyUbuntu, upcloud.OSFamilyDebian:
+		user.Groups = []string{"sudo"}
+	case upcloud.OSFamilyRHEL:
+		user.Groups = []string{"wheel"}
+	}
+
+	if err := ValidateDockerVersion(opts.DockerVersion); err != nil {
+		return nil, err
+	}
+	install := opts.DockerInstall
+	if install == "" {
+		install = DockerInstallRepository
+	}
+	installDocker, err := installDockerScript(opts.Family, install, opts.DockerVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	machine := &MachineInfo{
+		MachineID:       opts.MachineID,
+		Hostname:        Hostname(opts.MachineID),
+		ProviderVersion: opts.ProviderVersion,
+		Plan:            opts.Plan,
+		Zone:            opts.Zone,
+		CreatedAt:       opts.CreatedAt.UTC(),
+	}
+	machineInfo, err := machine.render()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{
+		Hostname:       machine.Hostname,
+		ManageEtcHosts: true,
+		DefaultUser:    true,
+		Users:          []User{user},
+		WriteFiles: []File{
+			{
+				Path:        InstallDockerPath,
+				Content:     installDocker,
+				Permissions: "0755",
+			},
+			{
+				Path:        MachineInfoPath,
+				Content:     machineInfo,
+				Permissions: "0644",
+			},
+			{
+				Path:        "/etc/motd",
+				Content:     machine.motd(),
+				Permissions: "0644",
+			},
+			{
+				Path:        BootstrapScriptPath,
+				Content:     bootstrapScript(opts.DockerVersion),
+				Permissions: "0755",
+			},
+		},
+		RunCmd: []string{BootstrapScriptPath},
+	}
+
+	if opts.SwapSize > 0 {
+		config.Swap = &Swap{
+			Filename: SwapFilePath,
+			Size:     fmt.Sprintf("%dM", opts.SwapSize),
+			MaxSize:  fmt.Sprintf("%dM", opts.SwapSize),
+		}
+	}
+
+	if len(opts.Sysctl) > 0 {
+		config.WriteFiles = append(config.WriteFiles, File{
+			Path:        SysctlPath,
+			Content:     renderSysctl(opts.Sysctl),
+			Permissions: "0644",
+		})
+		config.RunCmd = append([]string{"sysctl -p " + SysctlPath}, config.RunCmd...)
+	}
+
+	// write_files runs before runcmd, so Docker starts with this configuration
+	if opts.Docker != nil && !opts.Docker.IsEmpty() {
+		daemon, err := opts.Docker.Render()
+		if err != nil {
+			return nil, fmt.Errorf("invalid docker daemon configuration: %w", err)
+		}
+		config.WriteFiles = append(config.WriteFiles, File{
+			Path:        DockerDaemonPath,
+			Content:     daemon,
+			Permissions: "0644",
+		})
+	}
+
+	return config, nil
+}
+
+// bootstrapScript returns the script running the bootstrap steps, logging to
+// BootstrapLogPath and leaving a marker the status command checks for
+func bootstrapScript(dockerVersion string) string {
+	verifyDocker := `docker version --format 'Docker {{.Server.Version}} is running'`
+	if dockerVersion != "" {
+		verifyDocker = `running=$(docker version --format '{{.Server.Version}}')
+case "$running" in
+    ` + dockerVersion + ` | ` + dockerVersion + `.*)
+        echo "Docker $running is running"
+        ;;
+    *)
+        echo "Expected Docker ` + dockerVersion + `, but Docker $running is running"
+        exit 1
+        ;;
+esac`
+	}
+
+	return `#!/bin/bash
+set -eo pipefail
+
+exec >> ` + BootstrapLogPath + ` 2>&1
+mkdir -p ` + StateDir + `
+rm -f ` + BootstrapDoneMarker + ` ` + BootstrapFailedMarker + `
+trap 'echo "Command failed: $BASH_COMMAND"' ERR
+trap 'status=$?; if [ $status -ne 0 ]; then echo "Bootstrap failed with exit code $status"; touch ` + BootstrapFailedMarker + `; fi' EXIT
+
+echo "Bootstrap started at $(date -u +%Y-%m-%dT%H:%M:%SZ)"
+
+` + InstallDockerPath + `
+systemctl enable --now docker
+` + verifyDocker + `
+usermod -aG docker ` + BootstrapUser + `
+
+mkdir -p ` + AgentDir + `
+chown -R ` + BootstrapUser + `:` + BootstrapUser + ` ` + AgentDir + `
+
+echo "Bootstrap finished at $(date -u +%Y-%m-%dT%H:%M:%SZ)"
+touch ` + BootstrapDoneMarker + `
+`
+}
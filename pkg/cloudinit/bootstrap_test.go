@@ -0,0 +1,215 @@
+package cloudinit
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestBootstrap(t *testing.T) {
+	families := []upcloud.OSFamily{
+		upcloud.OSFamilyUbuntu,
+		upcloud.OSFamilyDebian,
+		upcloud.OSFamilyRHEL,
+		upcloud.OSFamilyUnknown,
+	}
+
+	for _, family := range families {
+		t.Run(string(family), func(t *testing.T) {
+			config, err := Bootstrap(&BootstrapOptions{
+				MachineID:       "devpod-test",
+				Family:          family,
+				ProviderVersion: "v0.3.0",
+				Plan:            "DEV-2xCPU-4GB",
+				Zone:            "de-fra1",
+				CreatedAt:       time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			})
+			if err != nil {
+				t.Fatalf("Failed to build bootstrap: %v", err)
+			}
+			got, err := config.Render()
+			if err != nil {
+				t.Fatalf("Failed to render bootstrap: %v", err)
+			}
+			assertGolden(t, filepath.Join("testdata", "bootstrap-"+string(family)+".yaml"), got)
+			assertScriptsParse(t, config)
+		})
+	}
+}
+
+func TestBootstrapDockerInstall(t *testing.T) {
+	tests := []struct {
+		name      string
+		family    upcloud.OSFamily
+		install   DockerInstall
+		version   string
+		wantError bool
+	}{
+		{"ubuntu-pinned", upcloud.OSFamilyUbuntu, DockerInstallRepository, "27.3.1", false},
+		{"rhel-pinned", upcloud.OSFamilyRHEL, DockerInstallRepository, "27.3", false},
+		{"debian-distro", upcloud.OSFamilyDebian, DockerInstallDistro, "", false},
+		{"ubuntu-preinstalled", upcloud.OSFamilyUbuntu, DockerInstallPreinstalled, "27", false},
+		{"rhel-distro", upcloud.OSFamilyRHEL, DockerInstallDistro, "", true},
+		{"invalid-version", upcloud.OSFamilyUbuntu, DockerInstallRepository, "latest", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := Bootstrap(&BootstrapOptions{
+				MachineID:     "devpod-test",
+				Family:        tt.family,
+				DockerInstall: tt.install,
+				DockerVersion: tt.version,
+			})
+			if tt.wantError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to build bootstrap: %v", err)
+			}
+			got, err := config.Render()
+			if err != nil {
+				t.Fatalf("Failed to render bootstrap: %v", err)
+			}
+			assertGolden(t, filepath.Join("testdata", "bootstrap-docker-"+tt.name+".yaml"), got)
+			assertScriptsParse(t, config)
+		})
+	}
+}
+
+func TestBootstrapTuning(t *testing.T) {
+	sysctl, err := Sysctl(string(SysctlProfileDevPod), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := Bootstrap(&BootstrapOptions{
+		MachineID: "devpod-test",
+		Family:    upcloud.OSFamilyUbuntu,
+		SwapSize:  2048,
+		Sysctl:    sysctl,
+	})
+	if err != nil {
+		t.Fatalf("Failed to build bootstrap: %v", err)
+	}
+	got, err := config.Render()
+	if err != nil {
+		t.Fatalf("Failed to render bootstrap: %v", err)
+	}
+	assertGolden(t, filepath.Join("testdata", "bootstrap-tuning.yaml"), got)
+}
+
+func TestParseDockerInstall(t *testing.T) {
+	tests := []struct {
+		value     string
+		want      DockerInstall
+		wantError bool
+	}{
+		{"", DockerInstallRepository, false},
+		{"repository", DockerInstallRepository, false},
+		{"Distro", DockerInstallDistro, false},
+		{"preinstalled", DockerInstallPreinstalled, false},
+		{"get.docker.com", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDockerInstall(tt.value)
+		if tt.wantError != (err != nil) || got != tt.want {
+			t.Errorf("ParseDockerInstall(%q) = %s, %v, want %s", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestHostname(t *testing.T) {
+	tests := []struct {
+		machineID string
+		want      string
+	}{
+		{"devpod-my-project", "devpod-my-project"},
+		{"DevPod_My.Project", "devpod-my-project"},
+		{"devpod-" + strings.Repeat("a", 70), "devpod-" + strings.Repeat("a", 56)},
+		{"devpod-" + strings.Repeat("a", 55) + "-b", "devpod-" + strings.Repeat("a", 55)},
+		{"___", "devpod"},
+	}
+
+	for _, tt := range tests {
+		if got := Hostname(tt.machineID); got != tt.want {
+			t.Errorf("Hostname(%q) = %q, want %q", tt.machineID, got, tt.want)
+		}
+	}
+}
+
+// assertScriptsParse checks that the bash scripts written by a config parse
+func assertScriptsParse(t *testing.T, config *Config) {
+	t.Helper()
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		return
+	}
+	for _, file := range config.WriteFiles {
+		if !strings.HasPrefix(file.Content, "#!/bin/bash") {
+			continue
+		}
+		check := exec.Command(bash, "-n")
+		check.Stdin = strings.NewReader(file.Content)
+		if out, err := check.CombinedOutput(); err != nil {
+			t.Errorf("%s is not valid bash: %v\n%s", file.Path, err, out)
+		}
+	}
+}
+
+func TestBootstrapDockerDaemon(t *testing.T) {
+	config, err := Bootstrap(&BootstrapOptions{
+		MachineID: "devpod-test",
+		Family:    upcloud.OSFamilyUbuntu,
+		Docker: &DockerDaemon{
+			RegistryMirrors:     []string{"https://mirror.gcr.io"},
+			LogDriver:           "json-file",
+			LogOpts:             map[string]string{"max-size": "10m", "max-file": "3"},
+			DataRoot:            "/mnt/data/docker",
+			DefaultAddressPools: []AddressPool{{Base: "10.200.0.0/16", Size: 24}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build bootstrap: %v", err)
+	}
+	got, err := config.Render()
+	if err != nil {
+		t.Fatalf("Failed to render bootstrap: %v", err)
+	}
+	assertGolden(t, filepath.Join("testdata", "bootstrap-docker.yaml"), got)
+
+	if _, err := Bootstrap(&BootstrapOptions{Docker: &DockerDaemon{DataRoot: "docker"}}); err == nil {
+		t.Error("Expected error for an invalid daemon configuration")
+	}
+}
+
+// assertGolden compares output with a golden file, rewriting it with -update
+func assertGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Output does not match %s\n--- got ---\n%s", golden, got)
+	}
+}
@@ -0,0 +1,152 @@
+// Package cloudinit builds the user data passed to UpCloud cloud-init
+// templates. Configurations are modelled as typed cloud-config documents and
+// rendered either as plain #cloud-config YAML or as a multipart MIME archive.
+package cloudinit
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxUserDataSize is the largest user data UpCloud accepts when creating a server
+const MaxUserDataSize = 64 * 1024
+
+// CloudConfigHeader is the first line of every cloud-config document
+const CloudConfigHeader = "#cloud-config\n"
+
+// Config is a cloud-config document. Only the modules the provider uses are
+// modelled; empty fields are left out of the rendered document.
+type Config struct {
+	Hostname string `yaml:"hostname,omitempty"`
+	// ManageEtcHosts maps the hostname to the loopback address in /etc/hosts
+	ManageEtcHosts bool `yaml:"manage_etc_hosts,omitempty"`
+
+	// DefaultUser keeps the distribution's default user in addition to Users
+	DefaultUser bool     `yaml:"-"`
+	Groups      []string `yaml:"groups,omitempty"`
+	Users       []User   `yaml:"-"`
+
+	PackageUpdate bool     `yaml:"package_update,omitempty"`
+	Packages      []string `yaml:"packages,omitempty"`
+
+	WriteFiles []File     `yaml:"write_files,omitempty"`
+	Mounts     [][]string `yaml:"mounts,omitempty"`
+	Swap       *Swap      `yaml:"swap,omitempty"`
+
+	// RunCmd commands run once, in order, at the end of the first boot
+	RunCmd []string `yaml:"runcmd,omitempty"`
+}
+
+// User is an entry of the cloud-config users list
+type User struct {
+	Name              string   `yaml:"name"`
+	Shell             string   `yaml:"shell,omitempty"`
+	Groups            []string `yaml:"groups,omitempty,flow"`
+	Sudo              string   `yaml:"sudo,omitempty"`
+	LockPasswd        bool     `yaml:"lock_passwd,omitempty"`
+	SSHAuthorizedKeys []string `yaml:"ssh_authorized_keys,omitempty"`
+}
+
+// File is an entry of the cloud-config write_files list
+type File struct {
+	Path        string `yaml:"path"`
+	Content     string `yaml:"content"`
+	Owner       string `yaml:"owner,omitempty"`
+	Permissions string `yaml:"permissions,omitempty"`
+	Append      bool   `yaml:"append,omitempty"`
+	// Defer writes the file after users and packages are set up, so it can be
+	// owned by a user created by the same document
+	Defer bool `yaml:"defer,omitempty"`
+}
+
+// Swap configures a swap file
+type Swap struct {
+	Filename string `yaml:"filename"`
+	Size     string `yaml:"size"`
+	MaxSize  string `yaml:"maxsize,omitempty"`
+}
+
+// Validate checks the document for mistakes cloud-init would only report on
+// the server, after the workspace has been created
+func (c *Config) Validate() error {
+	users := make(map[string]bool, len(c.Users))
+	for _, user := range c.Users {
+		if user.Name == "" {
+			return fmt.Errorf("user without a name")
+		}
+		if user.Name == "default" {
+			return fmt.Errorf("use DefaultUser to keep the default user")
+		}
+		if users[user.Name] {
+			return fmt.Errorf("duplicate user %s", user.Name)
+		}
+		users[user.Name] = true
+	}
+
+	files := make(map[string]bool, len(c.WriteFiles))
+	for _, file := range c.WriteFiles {
+		if !path.IsAbs(file.Path) {
+			return fmt.Errorf("file path %q is not absolute", file.Path)
+		}
+		if files[file.Path] && !file.Append {
+			return fmt.Errorf("file %s is written twice", file.Path)
+		}
+		files[file.Path] = true
+	}
+
+	for _, mount := range c.Mounts {
+		if len(mount) < 2 || len(mount) > 6 {
+			return fmt.Errorf("mount %v must have between 2 and 6 fields", mount)
+		}
+	}
+
+	if c.Swap != nil && (c.Swap.Filename == "" || c.Swap.Size == "") {
+		return fmt.Errorf("swap requires a filename and a size")
+	}
+
+	return nil
+}
+
+// Render validates the document and renders it as #cloud-config YAML
+func (c *Config) Render() ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cloud-config: %w", err)
+	}
+
+	// The users list mixes the "default" string with user mappings
+	type document struct {
+		*Config `yaml:",inline"`
+		Users   []interface{} `yaml:"users,omitempty"`
+	}
+	doc := document{Config: c}
+	if c.DefaultUser {
+		doc.Users = append(doc.Users, "default")
+	}
+	for _, user := range c.Users {
+		doc.Users = append(doc.Users, user)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(CloudConfigHeader)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("render cloud-config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("render cloud-config: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// CheckSize checks that user data fits within UpCloud's limit
+func CheckSize(userData []byte) error {
+	if len(userData) > MaxUserDataSize {
+		return fmt.Errorf("user data is %d bytes, UpCloud accepts at most %d bytes", len(userData), MaxUserDataSize)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package cloudinit
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		wantError bool
+	}{
+		{"Empty", Config{}, false},
+		{"Valid", Config{
+			Users:      []User{{Name: "devpod"}},
+			WriteFiles: []File{{Path: "/etc/motd"}},
+			Mounts:     [][]string{{"/dev/vdb", "/data"}},
+			Swap:       &Swap{Filename: "/swapfile", Size: "2G"},
+		}, false},
+		{"User without name", Config{Users: []User{{Shell: "/bin/bash"}}}, true},
+		{"Default user in list", Config{Users: []User{{Name: "default"}}}, true},
+		{"Duplicate user", Config{Users: []User{{Name: "devpod"}, {Name: "devpod"}}}, true},
+		{"Relative file path", Config{WriteFiles: []File{{Path: "etc/motd"}}}, true},
+		{"File written twice", Config{WriteFiles: []File{{Path: "/etc/motd"}, {Path: "/etc/motd"}}}, true},
+		{"File appended", Config{WriteFiles: []File{{Path: "/etc/motd"}, {Path: "/etc/motd", Append: true}}}, false},
+		{"Short mount", Config{Mounts: [][]string{{"/dev/vdb"}}}, true},
+		{"Swap without size", Config{Swap: &Swap{Filename: "/swapfile"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	config := &Config{
+		Hostname:    "devpod-test",
+		DefaultUser: true,
+		Users:       []User{{Name: "devpod", Groups: []string{"sudo"}}},
+		RunCmd:      []string{"echo done"},
+	}
+
+	got, err := config.Render()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(got), CloudConfigHeader) {
+		t.Errorf("Rendered document does not start with %q", CloudConfigHeader)
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(got, &doc); err != nil {
+		t.Fatalf("Rendered document is not valid YAML: %v", err)
+	}
+	users, ok := doc["users"].([]interface{})
+	if !ok || len(users) != 2 || users[0] != "default" {
+		t.Errorf("users = %v, want the default user followed by devpod", doc["users"])
+	}
+	if _, ok := doc["swap"]; ok {
+		t.Error("Empty swap should not be rendered")
+	}
+}
+
+func TestRenderMultipart(t *testing.T) {
+	parts := []Part{
+		{Filename: "bootstrap.yaml", ContentType: ContentTypeCloudConfig, Content: []byte("#cloud-config\n")},
+		{Filename: "user.sh", ContentType: ContentTypeShellScript, Content: []byte("#!/bin/sh\necho hi\n")},
+	}
+
+	got, err := RenderMultipart(parts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertGolden(t, "testdata/multipart.txt", got)
+
+	if _, err := RenderMultipart(nil); err == nil {
+		t.Error("Expected error for no parts")
+	}
+
+	injected := []Part{{
+		Filename:    "user.sh",
+		ContentType: ContentTypeShellScript,
+		Content:     []byte("#!/bin/sh\necho hi\n--" + boundary + "\nContent-Type: text/cloud-config\n"),
+	}}
+	if _, err := RenderMultipart(injected); err == nil {
+		t.Error("Expected error for a part containing the boundary")
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	if err := CheckSize(make([]byte, MaxUserDataSize)); err != nil {
+		t.Errorf("Unexpected error at the limit: %v", err)
+	}
+	if err := CheckSize(make([]byte, MaxUserDataSize+1)); err == nil {
+		t.Error("Expected error above the limit")
+	}
+}
@@ -0,0 +1,124 @@
+package cloudinit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DockerDaemonPath is where the Docker daemon configuration is written
+const DockerDaemonPath = "/etc/docker/daemon.json"
+
+var logSizePattern = regexp.MustCompile(`^\d+[kmg]?$`)
+
+// DockerDaemon is the subset of /etc/docker/daemon.json the provider
+// configures. Empty fields keep Docker's defaults.
+type DockerDaemon struct {
+	RegistryMirrors     []string          `json:"registry-mirrors,omitempty"`
+	InsecureRegistries  []string          `json:"insecure-registries,omitempty"`
+	LogDriver           string            `json:"log-driver,omitempty"`
+	LogOpts             map[string]string `json:"log-opts,omitempty"`
+	DataRoot            string            `json:"data-root,omitempty"`
+	DefaultAddressPools []AddressPool     `json:"default-address-pools,omitempty"`
+}
+
+// AddressPool is a range Docker allocates network subnets of the given size from
+type AddressPool struct {
+	Base string `json:"base"`
+	Size int    `json:"size"`
+}
+
+// ParseAddressPool parses an address pool written as "<base>/<prefix>:<size>",
+// for example "10.200.0.0/16:24". Without a size, /24 subnets are used.
+func ParseAddressPool(value string) (AddressPool, error) {
+	base, size, found := strings.Cut(value, ":")
+	pool := AddressPool{Base: base, Size: 24}
+	if found {
+		var err error
+		pool.Size, err = strconv.Atoi(size)
+		if err != nil {
+			return AddressPool{}, fmt.Errorf("invalid address pool %q: subnet size %q is not a number", value, size)
+		}
+	}
+
+	return pool, pool.validate()
+}
+
+func (p AddressPool) validate() error {
+	_, network, err := net.ParseCIDR(p.Base)
+	if err != nil {
+		return fmt.Errorf("invalid address pool base %q: %w", p.Base, err)
+	}
+
+	prefix, bits := network.Mask.Size()
+	if p.Size < prefix || p.Size > bits {
+		return fmt.Errorf("invalid address pool %s: subnet size /%d must be between /%d and /%d", p.Base, p.Size, prefix, bits)
+	}
+	return nil
+}
+
+// IsEmpty checks if the daemon configuration changes nothing
+func (d *DockerDaemon) IsEmpty() bool {
+	return len(d.RegistryMirrors) == 0 && len(d.InsecureRegistries) == 0 &&
+		d.LogDriver == "" && len(d.LogOpts) == 0 && d.DataRoot == "" &&
+		len(d.DefaultAddressPools) == 0
+}
+
+// Validate checks the settings Docker would otherwise refuse to start with
+func (d *DockerDaemon) Validate() error {
+	for _, mirror := range d.RegistryMirrors {
+		u, err := url.Parse(mirror)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("registry mirror %q must be an http or https URL", mirror)
+		}
+	}
+
+	for _, registry := range d.InsecureRegistries {
+		if registry == "" || strings.Contains(registry, "://") {
+			return fmt.Errorf("insecure registry %q must be a host[:port] or CIDR without a scheme", registry)
+		}
+	}
+
+	// Only the file based drivers rotate logs
+	if len(d.LogOpts) > 0 && d.LogDriver != "" && d.LogDriver != "json-file" && d.LogDriver != "local" {
+		return fmt.Errorf("log rotation is not supported by the %s log driver", d.LogDriver)
+	}
+	if maxSize, ok := d.LogOpts["max-size"]; ok && !logSizePattern.MatchString(maxSize) {
+		return fmt.Errorf("log max-size %q must be a size such as 10m", maxSize)
+	}
+	if maxFile, ok := d.LogOpts["max-file"]; ok {
+		if n, err := strconv.Atoi(maxFile); err != nil || n < 1 {
+			return fmt.Errorf("log max-file %q must be a positive number", maxFile)
+		}
+	}
+
+	if d.DataRoot != "" && !path.IsAbs(d.DataRoot) {
+		return fmt.Errorf("docker data-root %q must be an absolute path", d.DataRoot)
+	}
+
+	for _, pool := range d.DefaultAddressPools {
+		if err := pool.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Render renders the daemon configuration as daemon.json
+func (d *DockerDaemon) Render() (string, error) {
+	if err := d.Validate(); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("render %s: %w", DockerDaemonPath, err)
+	}
+	return string(data) + "\n", nil
+}
@@ -0,0 +1,164 @@
+package cloudinit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+// DockerInstall selects where the bootstrap installs Docker from
+type DockerInstall string
+
+// Docker install sources
+const (
+	// DockerInstallRepository installs from Docker's apt or dnf repository,
+	// verifying the repository key
+	DockerInstallRepository DockerInstall = "repository"
+	// DockerInstallDistro installs the distribution's own docker.io package
+	DockerInstallDistro DockerInstall = "distro"
+	// DockerInstallPreinstalled expects Docker in the image, for example one
+	// built with 'image build', and installs nothing
+	DockerInstallPreinstalled DockerInstall = "preinstalled"
+)
+
+// Fingerprints of the keys signing Docker's repositories
+const (
+	dockerAptKeyFingerprint = "9DC858229FC7DD38854AE2D88D81803C0EBFCD88"
+	dockerRpmKeyFingerprint = "060A61C51B558A7F742B77AAC52FEB6B621E9F35"
+)
+
+var dockerVersionPattern = regexp.MustCompile(`^\d+(\.\d+){0,2}$`)
+
+// ParseDockerInstall parses an install source, defaulting to the repository
+func ParseDockerInstall(value string) (DockerInstall, error) {
+	switch install := DockerInstall(strings.ToLower(value)); install {
+	case "":
+		return DockerInstallRepository, nil
+	case DockerInstallRepository, DockerInstallDistro, DockerInstallPreinstalled:
+		return install, nil
+	default:
+		return "", fmt.Errorf("unknown docker install source %q, expected %s, %s or %s",
+			value, DockerInstallRepository, DockerInstallDistro, DockerInstallPreinstalled)
+	}
+}
+
+// ValidateDockerVersion checks a Docker version such as 27, 27.3 or 27.3.1.
+// Partial versions match the newest matching release.
+func ValidateDockerVersion(version string) error {
+	if version != "" && !dockerVersionPattern.MatchString(version) {
+		return fmt.Errorf("invalid docker version %q, expected a version such as 27.3.1", version)
+	}
+	return nil
+}
+
+// installDockerScript returns the script installing Docker for an OS family
+func installDockerScript(family upcloud.OSFamily, install DockerInstall, version string) (string, error) {
+	if install == DockerInstallPreinstalled {
+		return `#!/bin/bash
+set -eo pipefail
+
+if ! command -v docker > /dev/null 2>&1; then
+    echo "Docker is not installed, but the image is expected to provide it"
+    exit 1
+fi
+`, nil
+	}
+
+	apt, dnf := installDockerAptRepository, installDockerDnfRepository
+	if install == DockerInstallDistro {
+		apt, dnf = installDockerAptDistro, installDockerDnfDistro
+		if family == upcloud.OSFamilyRHEL {
+			return "", fmt.Errorf("%s images do not package Docker, use the %s install source", family, DockerInstallRepository)
+		}
+	}
+
+	var script string
+	switch family {
+	case upcloud.OSFamilyUbuntu, upcloud.OSFamilyDebian:
+		script = apt
+	case upcloud.OSFamilyRHEL:
+		script = dnf
+	default:
+		script = `if command -v dnf > /dev/null 2>&1; then
+` + indent(dnf) + `
+else
+` + indent(apt) + `
+fi`
+	}
+
+	return `#!/bin/bash
+set -eo pipefail
+
+DOCKER_VERSION="` + version + `"
+
+if command -v docker > /dev/null 2>&1; then
+    exit 0
+fi
+
+# verify_key checks the fingerprint of a downloaded repository key
+verify_key() {
+    fingerprint=$(gpg --show-keys --with-colons "$1" | awk -F: '$1 == "fpr" { print $10; exit }')
+    if [ "$fingerprint" != "$2" ]; then
+        echo "Unexpected fingerprint $fingerprint for $1, expected $2"
+        exit 1
+    fi
+}
+
+` + script + "\n", nil
+}
+
+// installDockerAptRepository installs Docker from Docker's apt repository
+const installDockerAptRepository = `. /etc/os-release
+apt-get update
+apt-get install -y ca-certificates curl gnupg
+install -m 0755 -d /etc/apt/keyrings
+curl -fsSL "https://download.docker.com/linux/$ID/gpg" -o /tmp/docker.asc
+verify_key /tmp/docker.asc ` + dockerAptKeyFingerprint + `
+gpg --dearmor --yes -o /etc/apt/keyrings/docker.gpg /tmp/docker.asc
+chmod a+r /etc/apt/keyrings/docker.gpg
+echo "deb [arch=$(dpkg --print-architecture) signed-by=/etc/apt/keyrings/docker.gpg] https://download.docker.com/linux/$ID $VERSION_CODENAME stable" > /etc/apt/sources.list.d/docker.list
+apt-get update
+packages="docker-ce docker-ce-cli"
+if [ -n "$DOCKER_VERSION" ]; then
+    pkgver=$(apt-cache madison docker-ce | awk '{ print $3 }' | grep -E "^([0-9]+:)?${DOCKER_VERSION//./\\.}[.-]" | head -n 1)
+    if [ -z "$pkgver" ]; then
+        echo "Docker $DOCKER_VERSION is not available from the Docker repository"
+        exit 1
+    fi
+    packages="docker-ce=$pkgver docker-ce-cli=$pkgver"
+fi
+apt-get install -y $packages containerd.io docker-buildx-plugin docker-compose-plugin`
+
+// installDockerDnfRepository installs Docker from Docker's dnf repository.
+// RHEL-family distributions use the CentOS packages.
+const installDockerDnfRepository = `dnf install -y dnf-plugins-core
+curl -fsSL https://download.docker.com/linux/centos/gpg -o /tmp/docker.asc
+verify_key /tmp/docker.asc ` + dockerRpmKeyFingerprint + `
+rpm --import /tmp/docker.asc
+dnf config-manager --add-repo https://download.docker.com/linux/centos/docker-ce.repo
+packages="docker-ce docker-ce-cli"
+if [ -n "$DOCKER_VERSION" ]; then
+    pkgver=$(dnf list --showduplicates --quiet docker-ce | awk '{ print $2 }' | grep -E "^([0-9]+:)?${DOCKER_VERSION//./\\.}[.-]" | tail -n 1)
+    if [ -z "$pkgver" ]; then
+        echo "Docker $DOCKER_VERSION is not available from the Docker repository"
+        exit 1
+    fi
+    # docker-ce and docker-ce-cli have different epochs, so match without it
+    packages="docker-ce-${pkgver#*:} docker-ce-cli-${pkgver#*:}"
+fi
+dnf install -y $packages containerd.io docker-buildx-plugin docker-compose-plugin`
+
+// installDockerAptDistro installs the distribution's Docker package
+const installDockerAptDistro = `apt-get update
+apt-get install -y docker.io`
+
+// installDockerDnfDistro fails, RHEL-family distributions ship Podman instead
+const installDockerDnfDistro = `echo "This distribution does not package Docker, use the repository install source"
+exit 1`
+
+// indent indents every line of a script snippet by four spaces
+func indent(snippet string) string {
+	return "    " + strings.ReplaceAll(snippet, "\n", "\n    ")
+}
@@ -0,0 +1,70 @@
+package cloudinit
+
+import (
+	"testing"
+)
+
+func TestParseAddressPool(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		want      AddressPool
+		wantError bool
+	}{
+		{"Base and size", "10.200.0.0/16:24", AddressPool{Base: "10.200.0.0/16", Size: 24}, false},
+		{"Default size", "172.30.0.0/16", AddressPool{Base: "172.30.0.0/16", Size: 24}, false},
+		{"Size smaller than base", "10.200.0.0/16:8", AddressPool{}, true},
+		{"Size too large", "10.200.0.0/16:33", AddressPool{}, true},
+		{"Invalid size", "10.200.0.0/16:x", AddressPool{}, true},
+		{"Invalid base", "10.200.0.0:24", AddressPool{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAddressPool(tt.value)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %v", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error for %q: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAddressPool(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDockerDaemonValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		daemon    DockerDaemon
+		wantError bool
+	}{
+		{"Empty", DockerDaemon{}, false},
+		{"Mirror", DockerDaemon{RegistryMirrors: []string{"https://mirror.gcr.io"}}, false},
+		{"Mirror without scheme", DockerDaemon{RegistryMirrors: []string{"mirror.gcr.io"}}, true},
+		{"Insecure registry", DockerDaemon{InsecureRegistries: []string{"registry.internal:5000", "10.0.0.0/8"}}, false},
+		{"Insecure registry with scheme", DockerDaemon{InsecureRegistries: []string{"http://registry.internal"}}, true},
+		{"Log rotation", DockerDaemon{LogDriver: "json-file", LogOpts: map[string]string{"max-size": "10m", "max-file": "3"}}, false},
+		{"Log rotation with journald", DockerDaemon{LogDriver: "journald", LogOpts: map[string]string{"max-size": "10m"}}, true},
+		{"Invalid max-size", DockerDaemon{LogOpts: map[string]string{"max-size": "10 MB"}}, true},
+		{"Invalid max-file", DockerDaemon{LogOpts: map[string]string{"max-file": "0"}}, true},
+		{"Relative data-root", DockerDaemon{DataRoot: "data/docker"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.daemon.Validate()
+			if tt.wantError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package cloudinit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MachineInfoPath is where the workspace metadata is written on the server
+const MachineInfoPath = "/etc/devpod/machine.json"
+
+// maxHostnameLength is the longest hostname label allowed by RFC 1123
+const maxHostnameLength = 63
+
+var invalidHostnameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// MachineInfo identifies the DevPod machine a server belongs to, for tooling
+// inside the workspace and for support
+type MachineInfo struct {
+	MachineID       string    `json:"machine_id"`
+	Hostname        string    `json:"hostname"`
+	ProviderVersion string    `json:"provider_version"`
+	Plan            string    `json:"plan"`
+	Zone            string    `json:"zone"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Hostname derives a valid hostname from a machine ID: lowercase letters,
+// digits and dashes, at most 63 characters
+func Hostname(machineID string) string {
+	hostname := invalidHostnameChars.ReplaceAllString(strings.ToLower(machineID), "-")
+	if len(hostname) > maxHostnameLength {
+		hostname = hostname[:maxHostnameLength]
+	}
+	hostname = strings.Trim(hostname, "-")
+	if hostname == "" {
+		return "devpod"
+	}
+	return hostname
+}
+
+// render renders the metadata as machine.json
+func (m *MachineInfo) render() (string, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("render %s: %w", MachineInfoPath, err)
+	}
+	return string(data) + "\n", nil
+}
+
+// motd renders the login message identifying the workspace
+func (m *MachineInfo) motd() string {
+	return fmt.Sprintf(`
+DevPod workspace %s
+  Plan:     %s
+  Zone:     %s
+  Created:  %s
+  Provider: devpod-provider-upcloud %s
+
+Workspace metadata is in %s
+
+`, m.MachineID, m.Plan, m.Zone, m.CreatedAt.UTC().Format(time.RFC3339), m.ProviderVersion, MachineInfoPath)
+}
+
+// ReadMachineInfo reads the workspace metadata written by the bootstrap. It
+// only succeeds on a workspace server.
+func ReadMachineInfo() (*MachineInfo, error) {
+	data, err := os.ReadFile(MachineInfoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &MachineInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", MachineInfoPath, err)
+	}
+	return info, nil
+}
@@ -0,0 +1,78 @@
+package cloudinit
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+)
+
+// MIME types of user data parts understood by cloud-init
+const (
+	ContentTypeCloudConfig = "text/cloud-config"
+	ContentTypeShellScript = "text/x-shellscript"
+)
+
+// boundary separates multipart parts. It is fixed so the same parts always
+// render to the same document, and parts containing it are rejected.
+const boundary = "devpod-upcloud-user-data-boundary"
+
+// Part is one document of a multipart user data archive
+type Part struct {
+	// Filename names the part in cloud-init logs
+	Filename    string
+	ContentType string
+	// MergeType optionally sets how a cloud-config part merges with the parts before it
+	MergeType string
+	Content   []byte
+}
+
+// RenderMultipart renders parts into a multipart MIME archive. cloud-init
+// processes the parts in the given order.
+func RenderMultipart(parts []Part) ([]byte, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("multipart user data requires at least one part")
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+
+	for _, part := range parts {
+		if part.ContentType == "" || part.Filename == "" {
+			return nil, fmt.Errorf("multipart user data part requires a filename and a content type")
+		}
+		if bytes.Contains(part.Content, []byte(boundary)) {
+			return nil, fmt.Errorf("multipart user data part %s contains the MIME boundary %q", part.Filename, boundary)
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", part.ContentType))
+		header.Set("MIME-Version", "1.0")
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", part.Filename))
+		if part.MergeType != "" {
+			header.Set("Merge-Type", part.MergeType)
+		}
+
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, fmt.Errorf("render multipart user data: %w", err)
+		}
+		if _, err := w.Write(part.Content); err != nil {
+			return nil, fmt.Errorf("render multipart user data: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("render multipart user data: %w", err)
+	}
+
+	var doc bytes.Buffer
+	fmt.Fprintf(&doc, "Content-Type: multipart/mixed; boundary=%q\n", boundary)
+	doc.WriteString("MIME-Version: 1.0\n\n")
+	doc.Write(body.Bytes())
+
+	return doc.Bytes(), nil
+}
@@ -0,0 +1,67 @@
+package cloudinit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BootstrapState is the progress of the workspace bootstrap on a server
+type BootstrapState string
+
+// Bootstrap states reported by BootstrapStatusScript
+const (
+	BootstrapRunning BootstrapState = "running"
+	BootstrapDone    BootstrapState = "done"
+	BootstrapFailed  BootstrapState = "failed"
+	// BootstrapUnknown means the server was not bootstrapped with markers,
+	// for example because it predates them
+	BootstrapUnknown BootstrapState = "unknown"
+)
+
+// BootstrapLogTailLines is how many log lines are reported for a failed bootstrap
+const BootstrapLogTailLines = 20
+
+// cloudInitOutputLog receives the output of every cloud-init stage
+const cloudInitOutputLog = "/var/log/cloud-init-output.log"
+
+// BootstrapStatusScript returns a script printing the bootstrap state on the
+// first line, followed by the end of the relevant log if it failed. A
+// bootstrap that never ran, because cloud-init finished without reaching it,
+// counts as failed.
+func BootstrapStatusScript() string {
+	return fmt.Sprintf(`if [ -f %[1]s ]; then
+    echo %[2]s
+elif [ -f %[3]s ]; then
+    echo %[4]s
+    tail -n %[5]d %[6]s
+elif [ -f %[7]s ]; then
+    if cloud-init status 2>/dev/null | grep -Eq "status: (done|error)"; then
+        echo %[4]s
+        tail -n %[5]d %[8]s
+    else
+        echo %[9]s
+    fi
+else
+    echo %[10]s
+fi
+`, BootstrapDoneMarker, BootstrapDone,
+		BootstrapFailedMarker, BootstrapFailed, BootstrapLogTailLines, BootstrapLogPath,
+		BootstrapScriptPath, cloudInitOutputLog, BootstrapRunning,
+		BootstrapUnknown)
+}
+
+// ParseBootstrapStatus parses the output of BootstrapStatusScript into the
+// state and the log tail of a failed bootstrap
+func ParseBootstrapStatus(output string) (BootstrapState, string) {
+	state, log, _ := strings.Cut(output, "\n")
+	switch BootstrapState(strings.TrimSpace(state)) {
+	case BootstrapDone:
+		return BootstrapDone, ""
+	case BootstrapFailed:
+		return BootstrapFailed, strings.TrimRight(log, "\n")
+	case BootstrapRunning:
+		return BootstrapRunning, ""
+	default:
+		return BootstrapUnknown, ""
+	}
+}
@@ -0,0 +1,52 @@
+package cloudinit
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseBootstrapStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		wantState BootstrapState
+		wantLog   string
+	}{
+		{"Done", "done\n", BootstrapDone, ""},
+		{"Running", "running\n", BootstrapRunning, ""},
+		{"Failed", "failed\nline 1\nline 2\n", BootstrapFailed, "line 1\nline 2"},
+		{"Unknown", "unknown\n", BootstrapUnknown, ""},
+		{"Empty", "", BootstrapUnknown, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, log := ParseBootstrapStatus(tt.output)
+			if state != tt.wantState || log != tt.wantLog {
+				t.Errorf("ParseBootstrapStatus(%q) = %s, %q, want %s, %q", tt.output, state, log, tt.wantState, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestBootstrapStatusScript(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Markers are absent on the test machine, so the script reports unknown
+	// unless the machine happens to be a workspace
+	if _, err := os.Stat(BootstrapScriptPath); err == nil {
+		t.Skip("running on a bootstrapped server")
+	}
+	out, err := exec.Command(sh, "-c", BootstrapStatusScript()).Output()
+	if err != nil {
+		t.Fatalf("Status script failed: %v", err)
+	}
+	if state, _ := ParseBootstrapStatus(string(out)); state != BootstrapUnknown {
+		t.Errorf("state = %s, want %s (output %q)", state, BootstrapUnknown, strings.TrimSpace(string(out)))
+	}
+}
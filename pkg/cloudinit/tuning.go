@@ -0,0 +1,119 @@
+package cloudinit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Swap and sysctl constants
+const (
+	// SwapFilePath is the swap file created by the bootstrap
+	SwapFilePath = "/swapfile"
+
+	// SysctlPath is the sysctl drop-in written by the bootstrap
+	SysctlPath = "/etc/sysctl.d/90-devpod.conf"
+
+	// SwapSizeAuto sizes the swap file from the plan
+	SwapSizeAuto = "auto"
+)
+
+// SysctlProfile is a named set of kernel parameters
+type SysctlProfile string
+
+// Sysctl profiles
+const (
+	// SysctlProfileDevPod raises the inotify limits file watchers in large
+	// repositories run into and keeps swap as a last resort
+	SysctlProfileDevPod SysctlProfile = "devpod"
+	// SysctlProfileNone keeps the distribution's defaults
+	SysctlProfileNone SysctlProfile = "none"
+)
+
+var (
+	sysctlProfiles = map[SysctlProfile]map[string]string{
+		SysctlProfileDevPod: {
+			"fs.inotify.max_user_watches":   "524288",
+			"fs.inotify.max_user_instances": "512",
+			"vm.swappiness":                 "10",
+		},
+		SysctlProfileNone: {},
+	}
+
+	sysctlKeyPattern = regexp.MustCompile(`^[a-z0-9_]+(\.[a-z0-9_-]+)+$`)
+	swapSizePattern  = regexp.MustCompile(`^(\d+)([MG])$`)
+)
+
+// ParseSwapSize parses a swap size option into MB. "auto" or an empty value
+// returns autoMB, "0" or "off" disables swap, and sizes are given as 2048M or
+// 2G.
+func ParseSwapSize(value string, autoMB int) (int, error) {
+	switch strings.ToLower(value) {
+	case "", SwapSizeAuto:
+		return autoMB, nil
+	case "0", "off", "none":
+		return 0, nil
+	}
+
+	match := swapSizePattern.FindStringSubmatch(strings.ToUpper(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid swap size %q, expected auto, off or a size such as 2G or 2048M", value)
+	}
+	size, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid swap size %q: %w", value, err)
+	}
+	if match[2] == "G" {
+		size *= 1024
+	}
+	return size, nil
+}
+
+// Sysctl returns the kernel parameters of a profile with overrides applied.
+// Overrides are key=value pairs, such as vm.swappiness=30.
+func Sysctl(profile string, overrides []string) (map[string]string, error) {
+	if profile == "" {
+		profile = string(SysctlProfileDevPod)
+	}
+	base, ok := sysctlProfiles[SysctlProfile(strings.ToLower(profile))]
+	if !ok {
+		return nil, fmt.Errorf("unknown sysctl profile %q, expected %s or %s", profile, SysctlProfileDevPod, SysctlProfileNone)
+	}
+
+	settings := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		settings[key] = value
+	}
+
+	for _, override := range overrides {
+		key, value, found := strings.Cut(override, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found || value == "" || strings.ContainsAny(value, "\n\r") {
+			return nil, fmt.Errorf("invalid sysctl setting %q, expected key=value", override)
+		}
+		if !sysctlKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid sysctl key %q", key)
+		}
+		settings[key] = value
+	}
+
+	return settings, nil
+}
+
+// renderSysctl renders kernel parameters as a sysctl.d file, sorted by key
+func renderSysctl(settings map[string]string) string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("# Managed by devpod-provider-upcloud\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s = %s\n", key, settings[key])
+	}
+	return b.String()
+}
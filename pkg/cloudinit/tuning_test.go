@@ -0,0 +1,66 @@
+package cloudinit
+
+import (
+	"testing"
+)
+
+func TestParseSwapSize(t *testing.T) {
+	tests := []struct {
+		value     string
+		want      int
+		wantError bool
+	}{
+		{"", 2048, false},
+		{"auto", 2048, false},
+		{"off", 0, false},
+		{"0", 0, false},
+		{"4G", 4096, false},
+		{"1536M", 1536, false},
+		{"1536m", 1536, false},
+		{"4 GB", 0, true},
+		{"-1G", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSwapSize(tt.value, 2048)
+		if tt.wantError != (err != nil) || got != tt.want {
+			t.Errorf("ParseSwapSize(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestSysctl(t *testing.T) {
+	settings, err := Sysctl("", []string{"vm.swappiness=30", "net.core.somaxconn = 1024"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if settings["vm.swappiness"] != "30" {
+		t.Errorf("vm.swappiness = %s, want override 30", settings["vm.swappiness"])
+	}
+	if settings["net.core.somaxconn"] != "1024" {
+		t.Errorf("net.core.somaxconn = %s, want 1024", settings["net.core.somaxconn"])
+	}
+	if settings["fs.inotify.max_user_watches"] == "" {
+		t.Error("devpod profile should raise fs.inotify.max_user_watches")
+	}
+
+	none, err := Sysctl("none", nil)
+	if err != nil || len(none) != 0 {
+		t.Errorf("Sysctl(none) = %v, %v, want no settings", none, err)
+	}
+
+	invalid := [][]string{
+		{"vm.swappiness"},
+		{"vm.swappiness="},
+		{"VM SWAPPINESS=1"},
+		{"swappiness=1"},
+	}
+	for _, overrides := range invalid {
+		if _, err := Sysctl("devpod", overrides); err == nil {
+			t.Errorf("Expected error for overrides %v", overrides)
+		}
+	}
+	if _, err := Sysctl("fast", nil); err == nil {
+		t.Error("Expected error for an unknown profile")
+	}
+}
@@ -0,0 +1,85 @@
+package cloudinit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeType tells cloud-init to add the keys of a cloud-config part to the
+// documents before it, appending to lists, rather than replacing them. This
+// keeps user-supplied runcmd or write_files from dropping the bootstrap's.
+const MergeType = "list(append)+dict(no_replace,recurse_list)+str()"
+
+// ParseUserData parses user-supplied user data given either inline or as the
+// path of a file. Cloud-config documents and shell scripts are supported, and
+// a single line is only read as a path if it has neither of their headers.
+func ParseUserData(value string) (*Part, error) {
+	content := []byte(value)
+	source := "UPCLOUD_USER_DATA"
+	inline := strings.HasPrefix(value, strings.TrimSpace(CloudConfigHeader)) || strings.HasPrefix(value, "#!")
+	if !inline && !strings.Contains(value, "\n") {
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("user data is neither a cloud-config document, a shell script nor a readable file: %w", err)
+		}
+		content = data
+		source = value
+	}
+
+	switch {
+	case strings.HasPrefix(string(content), strings.TrimSpace(CloudConfigHeader)):
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("user data from %s is not valid cloud-config: %w", source, err)
+		}
+		return &Part{
+			Filename:    "user-data.yaml",
+			ContentType: ContentTypeCloudConfig,
+			MergeType:   MergeType,
+			Content:     content,
+		}, nil
+	case strings.HasPrefix(string(content), "#!"):
+		return &Part{
+			Filename:    "user-data.sh",
+			ContentType: ContentTypeShellScript,
+			Content:     content,
+		}, nil
+	default:
+		return nil, fmt.Errorf("user data from %s must start with #cloud-config or a #! shebang", source)
+	}
+}
+
+// Merge renders the bootstrap together with optional user data. Without user
+// data the bootstrap is rendered as plain cloud-config; otherwise both are
+// combined into a multipart archive with the bootstrap first, so user
+// provisioning runs on top of it. The result is checked against UpCloud's
+// size limit.
+func Merge(bootstrap *Config, userData *Part) ([]byte, error) {
+	rendered, err := bootstrap.Render()
+	if err != nil {
+		return nil, err
+	}
+
+	if userData != nil {
+		rendered, err = RenderMultipart([]Part{
+			{
+				Filename:    "bootstrap.yaml",
+				ContentType: ContentTypeCloudConfig,
+				Content:     rendered,
+			},
+			*userData,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := CheckSize(rendered); err != nil {
+		return nil, err
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,91 @@
+package cloudinit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/upcloud"
+)
+
+func TestParseUserData(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "user-data.sh")
+	if err := os.WriteFile(file, []byte("#!/bin/sh\necho hi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		value           string
+		wantContentType string
+		wantError       bool
+	}{
+		{"Inline cloud-config", "#cloud-config\npackages:\n  - git\n", ContentTypeCloudConfig, false},
+		{"Inline shell script", "#!/bin/bash\necho hi\n", ContentTypeShellScript, false},
+		{"Inline one-line shell script", "#!/bin/sh", ContentTypeShellScript, false},
+		{"Inline one-line cloud-config", "#cloud-config", ContentTypeCloudConfig, false},
+		{"File", file, ContentTypeShellScript, false},
+		{"Invalid cloud-config", "#cloud-config\npackages: [git\n", "", true},
+		{"Unknown format", "packages:\n  - git\n", "", true},
+		{"Missing file", filepath.Join(dir, "missing.yaml"), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUserData(tt.value)
+			if tt.wantError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.ContentType != tt.wantContentType {
+				t.Errorf("ContentType = %s, want %s", got.ContentType, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	bootstrap, err := Bootstrap(&BootstrapOptions{MachineID: "devpod-test", Family: upcloud.OSFamilyUbuntu})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := Merge(bootstrap, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(string(plain), CloudConfigHeader) {
+		t.Error("Bootstrap without user data should be plain cloud-config")
+	}
+
+	userData, err := ParseUserData("#cloud-config\nruncmd:\n  - echo user\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := Merge(bootstrap, userData)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertGolden(t, filepath.Join("testdata", "merged.txt"), merged)
+
+	again, _ := Merge(bootstrap, userData)
+	if string(again) != string(merged) {
+		t.Error("Merged user data is not deterministic")
+	}
+
+	tooLarge := &Part{
+		Filename:    "user-data.sh",
+		ContentType: ContentTypeShellScript,
+		Content:     []byte("#!/bin/sh\n" + strings.Repeat("#", MaxUserDataSize)),
+	}
+	if _, err := Merge(bootstrap, tooLarge); err == nil {
+		t.Error("Expected error for user data above the size limit")
+	}
+}
@@ -76,22 +76,33 @@ type PlanRecommendations struct {
 
 // PlanMetadata contains metadata about the plans
 type PlanMetadata struct {
-	Provider         string   `yaml:"provider"`
-	APIVersion       string   `yaml:"api_version"`
-	Currency         string   `yaml:"currency"`
-	BillingUnit      string   `yaml:"billing_unit"`
-	RegionsAvailable []string `yaml:"regions_available"`
+	Provider          string   `yaml:"provider"`
+	APIVersion        string   `yaml:"api_version"`
+	Currency          string   `yaml:"currency"`
+	BillingUnit       string   `yaml:"billing_unit"`
+	RegionsAvailable  []string `yaml:"regions_available"`
+	EncryptionRegions []string `yaml:"encryption_regions"`
 }
 
 // LoadServerPlans loads the server plans from the embedded YAML file
 func LoadServerPlans() (*ServerPlans, error) {
+	return ParseServerPlans(serverPlansYAML)
+}
+
+// ParseServerPlans parses a server plans YAML file
+func ParseServerPlans(data []byte) (*ServerPlans, error) {
 	var plans ServerPlans
-	if err := yaml.Unmarshal(serverPlansYAML, &plans); err != nil {
+	if err := yaml.Unmarshal(data, &plans); err != nil {
 		return nil, fmt.Errorf("failed to parse server plans: %w", err)
 	}
 	return &plans, nil
 }
 
+// ServerPlansYAML returns the embedded server plans YAML file
+func ServerPlansYAML() []byte {
+	return serverPlansYAML
+}
+
 // GetPlanByID finds a plan by its ID across all categories
 func (s *ServerPlans) GetPlanByID(id string) (*ServerPlan, *PlanCategory, error) {
 	for _, category := range s.Categories {
@@ -247,29 +258,39 @@ func (s *ServerPlans) FormatPlanList(detailed bool) string {
 
 // GetPlanRecommendation returns a recommended plan based on criteria
 func (s *ServerPlans) GetPlanRecommendation(language, framework, workload string) string {
+	plan, _ := s.RecommendPlan(language, framework, workload)
+	return plan
+}
+
+// RecommendPlan returns a recommended plan and the selection rule that chose
+// it. Workload rules take precedence over framework rules, and framework rules
+// over language rules; without a matching rule the default plan is returned.
+func (s *ServerPlans) RecommendPlan(language, framework, workload string) (string, string) {
+	rules := s.SelectionRules.Recommendations
+
 	// Check workload first (highest priority)
 	if workload != "" {
-		if plan, exists := s.SelectionRules.Recommendations.ByWorkload[strings.ToLower(workload)]; exists {
-			return plan
+		if plan, exists := rules.ByWorkload[strings.ToLower(workload)]; exists {
+			return plan, fmt.Sprintf("the %s workload rule", strings.ToLower(workload))
 		}
 	}
 
 	// Check framework
 	if framework != "" {
-		if plan, exists := s.SelectionRules.Recommendations.ByFramework[strings.ToLower(framework)]; exists {
-			return plan
+		if plan, exists := rules.ByFramework[strings.ToLower(framework)]; exists {
+			return plan, fmt.Sprintf("the %s framework rule", strings.ToLower(framework))
 		}
 	}
 
 	// Check language
 	if language != "" {
-		if plan, exists := s.SelectionRules.Recommendations.ByLanguage[strings.ToLower(language)]; exists {
-			return plan
+		if plan, exists := rules.ByLanguage[strings.ToLower(language)]; exists {
+			return plan, fmt.Sprintf("the %s language rule", strings.ToLower(language))
 		}
 	}
 
 	// Return default
-	return s.SelectionRules.Recommendations.Default
+	return rules.Default, "the default rule"
 }
 
 // IsValidRegion checks if a region is valid
@@ -286,3 +307,33 @@ func (s *ServerPlans) IsValidRegion(region string) bool {
 func (s *ServerPlans) GetRegions() []string {
 	return s.Metadata.RegionsAvailable
 }
+
+// SupportsEncryption checks if storage encryption at rest is offered in a region
+func (s *ServerPlans) SupportsEncryption(region string) bool {
+	for _, r := range s.Metadata.EncryptionRegions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// RecommendedSwap returns the swap file size in MB for the plan on a disk of
+// storageGB: twice the RAM on plans up to 2 GB, half the RAM up to 8 GB and
+// 4 GB above that, never more than a quarter of the disk
+func (p *ServerPlan) RecommendedSwap(storageGB int) int {
+	var swap int
+	switch {
+	case p.RAM <= 2048:
+		swap = 2 * p.RAM
+	case p.RAM <= 8192:
+		swap = p.RAM / 2
+	default:
+		swap = 4096
+	}
+
+	if limit := storageGB * 1024 / 4; swap > limit {
+		swap = limit
+	}
+	return swap
+}
@@ -257,6 +257,36 @@ func TestGetPlanRecommendation(t *testing.T) {
 	}
 }
 
+func TestRecommendPlan(t *testing.T) {
+	plans, err := LoadServerPlans()
+	if err != nil {
+		t.Fatalf("Failed to load server plans: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		language   string
+		framework  string
+		workload   string
+		wantPlan   string
+		wantReason string
+	}{
+		{"Framework over language", "java", "spring", "", "DEV-2xCPU-16GB", "the spring framework rule"},
+		{"Workload over framework", "python", "django", "data_science", "HMEM-4xCPU-32GB", "the data_science workload rule"},
+		{"Case insensitive", "Rust", "", "", "DEV-2xCPU-8GB", "the rust language rule"},
+		{"Unknown language", "cobol", "", "", "DEV-2xCPU-4GB", "the default rule"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, reason := plans.RecommendPlan(tt.language, tt.framework, tt.workload)
+			if plan != tt.wantPlan || reason != tt.wantReason {
+				t.Errorf("RecommendPlan() = %v, %q, want %v, %q", plan, reason, tt.wantPlan, tt.wantReason)
+			}
+		})
+	}
+}
+
 func TestIsValidRegion(t *testing.T) {
 	plans, err := LoadServerPlans()
 	if err != nil {
@@ -386,3 +416,56 @@ func TestFormatPlanList(t *testing.T) {
 		t.Error("Detailed output should include use cases")
 	}
 }
+
+func TestSupportsEncryption(t *testing.T) {
+	plans, err := LoadServerPlans()
+	if err != nil {
+		t.Fatalf("Failed to load server plans: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		region string
+		want   bool
+	}{
+		{"Frankfurt", "de-fra1", true},
+		{"Helsinki", "fi-hel1", true},
+		{"New York without encryption", "us-nyc1", false},
+		{"Invalid region", "invalid-region", false},
+		{"Empty region", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := plans.SupportsEncryption(tt.region); got != tt.want {
+				t.Errorf("SupportsEncryption(%s) = %v, want %v", tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommendedSwap(t *testing.T) {
+	tests := []struct {
+		name      string
+		ram       int
+		storageGB int
+		want      int
+	}{
+		{"Half GB plan", 512, 10, 1024},
+		{"1 GB plan on small disk", 1024, 10, 2048},
+		{"2 GB plan", 2048, 50, 4096},
+		{"2 GB plan on small disk", 2048, 10, 2560},
+		{"4 GB plan", 4096, 50, 2048},
+		{"8 GB plan", 8192, 80, 4096},
+		{"32 GB plan", 32768, 200, 4096},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &ServerPlan{RAM: tt.ram}
+			if got := plan.RecommendedSwap(tt.storageGB); got != tt.want {
+				t.Errorf("RecommendedSwap(%d) with %d MB RAM = %d, want %d", tt.storageGB, tt.ram, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plan ID prefixes of the catalog categories. Plans starting with their core
+// count, such as 2xCPU-4GB, are general purpose plans.
+var categoryPrefixes = []struct {
+	prefix   string
+	category string
+}{
+	{"DEV-", "developer"},
+	{"CN-", "cloud_native"},
+	{"HICPU-", "high_cpu"},
+	{"HCPU-", "high_cpu"},
+	{"HIMEM-", "high_memory"},
+	{"HMEM-", "high_memory"},
+}
+
+// PlanDiff lists the differences between the catalog and the live plans
+type PlanDiff struct {
+	Added   []PlanChange `json:"added,omitempty" yaml:"added,omitempty"`
+	Removed []PlanChange `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Changed []PlanChange `json:"changed,omitempty" yaml:"changed,omitempty"`
+}
+
+// PlanChange is a plan added, removed or changed. Plan holds the live plan,
+// or the catalog plan for removed plans.
+type PlanChange struct {
+	Plan     ServerPlan    `json:"-" yaml:"-"`
+	ID       string        `json:"id" yaml:"id"`
+	Category string        `json:"category,omitempty" yaml:"category,omitempty"`
+	Fields   []FieldChange `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// FieldChange is a changed plan field, with values formatted as in the catalog
+type FieldChange struct {
+	Field string `json:"field" yaml:"field"`
+	Old   string `json:"old" yaml:"old"`
+	New   string `json:"new" yaml:"new"`
+}
+
+// IsEmpty checks if the catalog matches the live plans
+func (d *PlanDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// CategoryForPlan returns the catalog category of a plan from its ID, or an
+// empty string for plans the catalog has no category for, such as GPU plans
+func CategoryForPlan(id string) string {
+	for _, c := range categoryPrefixes {
+		if strings.HasPrefix(id, c.prefix) {
+			return c.category
+		}
+	}
+	if id != "" && id[0] >= '0' && id[0] <= '9' {
+		return "general_purpose"
+	}
+	return ""
+}
+
+// Diff compares the catalog with the live plans. Live plans hold the
+// specifications and prices only; prices of zero are not compared, and the
+// hourly price, which UpCloud derives from the monthly one, is only updated
+// along with it.
+func (s *ServerPlans) Diff(live []ServerPlan) *PlanDiff {
+	diff := &PlanDiff{}
+
+	liveByID := make(map[string]ServerPlan, len(live))
+	for _, plan := range live {
+		liveByID[plan.ID] = plan
+	}
+
+	catalog := map[string]bool{}
+	for categoryName, category := range s.Categories {
+		for _, plan := range category.Plans {
+			catalog[plan.ID] = true
+
+			livePlan, ok := liveByID[plan.ID]
+			if !ok {
+				diff.Removed = append(diff.Removed, PlanChange{Plan: plan, ID: plan.ID, Category: categoryName})
+				continue
+			}
+			if fields := compareFields(plan, livePlan); len(fields) > 0 {
+				diff.Changed = append(diff.Changed, PlanChange{Plan: livePlan, ID: plan.ID, Category: categoryName, Fields: fields})
+			}
+		}
+	}
+
+	for _, plan := range live {
+		if !catalog[plan.ID] {
+			diff.Added = append(diff.Added, PlanChange{Plan: plan, ID: plan.ID, Category: CategoryForPlan(plan.ID)})
+		}
+	}
+
+	for _, changes := range [][]PlanChange{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	}
+	return diff
+}
+
+// References returns the settings selecting a plan, such as the default plan
+// or a selection rule
+func (s *ServerPlans) References(id string) []string {
+	var references []string
+	if s.DefaultPlan == id {
+		references = append(references, "default_plan")
+	}
+
+	rules := s.SelectionRules.Recommendations
+	if rules.Default == id {
+		references = append(references, "selection_rules.recommendations.default")
+	}
+	for _, group := range []struct {
+		name  string
+		rules map[string]string
+	}{
+		{"by_language", rules.ByLanguage},
+		{"by_framework", rules.ByFramework},
+		{"by_workload", rules.ByWorkload},
+	} {
+		var keys []string
+		for key, plan := range group.rules {
+			if plan == id {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			references = append(references, fmt.Sprintf("selection_rules.recommendations.%s.%s", group.name, key))
+		}
+	}
+	return references
+}
+
+// compareFields returns the specification and price fields that differ
+func compareFields(catalog, live ServerPlan) []FieldChange {
+	var fields []FieldChange
+	compare := func(field, old, new string) {
+		if old != new {
+			fields = append(fields, FieldChange{Field: field, Old: old, New: new})
+		}
+	}
+
+	compare("cpu", strconv.Itoa(catalog.CPU), strconv.Itoa(live.CPU))
+	compare("ram", strconv.Itoa(catalog.RAM), strconv.Itoa(live.RAM))
+	compare("storage", strconv.Itoa(catalog.Storage), strconv.Itoa(live.Storage))
+	if live.PriceMonthly > 0 && formatMonthly(catalog.PriceMonthly) != formatMonthly(live.PriceMonthly) {
+		compare("price_monthly", formatMonthly(catalog.PriceMonthly), formatMonthly(live.PriceMonthly))
+		if live.PriceHourly > 0 {
+			compare("price_hourly", formatHourly(catalog.PriceHourly), formatHourly(live.PriceHourly))
+		}
+	}
+	return fields
+}
+
+func formatMonthly(price float32) string {
+	return fmt.Sprintf("%.2f", price)
+}
+
+// formatHourly formats an hourly price like the catalog: with four decimals,
+// or three if the fourth is zero
+func formatHourly(price float32) string {
+	formatted := fmt.Sprintf("%.4f", price)
+	return strings.TrimSuffix(formatted, "0")
+}
+
+// SyncPlansYAML applies a diff to a plans YAML file. The file is patched line
+// by line, so comments, formatting and curated fields such as descriptions,
+// use cases and selection rules are kept. Added plans without a category are
+// skipped, and the version and last_updated fields are set from now.
+func SyncPlansYAML(data []byte, diff *PlanDiff, now time.Time) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse server plans: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("failed to parse server plans: empty document")
+	}
+	root := document.Content[0]
+
+	lines := strings.Split(string(data), "\n")
+	var edits []lineEdit
+
+	if node := mappingValue(root, "version"); node != nil {
+		edits = append(edits, replaceScalar(lines, node, strconv.Quote(now.Format("2006.01"))))
+	}
+	if node := mappingValue(root, "last_updated"); node != nil {
+		edits = append(edits, replaceScalar(lines, node, strconv.Quote(now.Format("2006-01-02"))))
+	}
+
+	categories := mappingValue(root, "categories")
+	if categories == nil {
+		return nil, fmt.Errorf("server plans have no categories")
+	}
+
+	changed := map[string]PlanChange{}
+	for _, change := range diff.Changed {
+		changed[change.ID] = change
+	}
+	removed := map[string]bool{}
+	for _, change := range diff.Removed {
+		removed[change.ID] = true
+	}
+
+	for i := 0; i+1 < len(categories.Content); i += 2 {
+		categoryName := categories.Content[i].Value
+		plans := mappingValue(categories.Content[i+1], "plans")
+		if plans == nil || len(plans.Content) == 0 {
+			continue
+		}
+		if plans.Style&yaml.FlowStyle != 0 {
+			return nil, fmt.Errorf("plans of category %s must be a block sequence to be updated", categoryName)
+		}
+
+		for _, plan := range plans.Content {
+			id := mappingValue(plan, "id")
+			if id == nil {
+				continue
+			}
+			if removed[id.Value] {
+				edits = append(edits, removeLines(lines, plan.Line-1, lastLine(plan)))
+				continue
+			}
+			for _, field := range changed[id.Value].Fields {
+				if node := mappingValue(plan, field.Field); node != nil {
+					edits = append(edits, replaceScalar(lines, node, field.New))
+				}
+			}
+		}
+
+		var added []string
+		last := plans.Content[len(plans.Content)-1]
+		for _, change := range diff.Added {
+			if change.Category == categoryName {
+				added = append(added, renderPlan(change.Plan, last.Column))
+			}
+		}
+		if len(added) > 0 {
+			edits = append(edits, lineEdit{
+				start:  lastLine(last),
+				end:    lastLine(last),
+				insert: strings.Split("\n"+strings.Join(added, "\n\n"), "\n"),
+			})
+		}
+	}
+
+	// Apply the edits from the bottom so line numbers stay valid
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	for _, edit := range edits {
+		tail := append(append([]string{}, edit.insert...), lines[edit.end:]...)
+		lines = append(lines[:edit.start], tail...)
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// lineEdit replaces lines[start:end] with insert
+type lineEdit struct {
+	start, end int
+	insert     []string
+}
+
+// mappingValue returns the value of a key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// lastLine returns the 1-based number of the last line a node spans
+func lastLine(node *yaml.Node) int {
+	last := node.Line
+	for _, child := range node.Content {
+		if line := lastLine(child); line > last {
+			last = line
+		}
+	}
+	return last
+}
+
+// replaceScalar replaces a scalar value in place, keeping a trailing comment
+func replaceScalar(lines []string, node *yaml.Node, value string) lineEdit {
+	line := lines[node.Line-1]
+	start := node.Column - 1
+	end := len(line)
+	if comment := strings.Index(line[start:], " #"); comment >= 0 {
+		end = start + comment
+	}
+	token := strings.TrimRight(line[start:end], " ")
+
+	return lineEdit{
+		start:  node.Line - 1,
+		end:    node.Line,
+		insert: []string{line[:start] + value + line[start+len(token):]},
+	}
+}
+
+// removeLines removes the lines of a sequence item and the blank line after it
+func removeLines(lines []string, start, last int) lineEdit {
+	end := last
+	if end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+		end++
+	}
+	return lineEdit{start: start, end: end}
+}
+
+// renderPlan renders a plan as a sequence item whose keys start at column,
+// or as close to it as a sequence item allows
+func renderPlan(plan ServerPlan, column int) string {
+	indent := strings.Repeat(" ", max(column-1, 2))
+	description := fmt.Sprintf("%d CPU, %d GB RAM", plan.CPU, plan.RAM/1024)
+	if plan.Storage > 0 {
+		description += fmt.Sprintf(", %d GB storage", plan.Storage)
+	}
+
+	fields := []string{
+		"id: " + strconv.Quote(plan.ID),
+		"display_name: " + strconv.Quote(plan.ID),
+		"description: " + strconv.Quote(description),
+		"cpu: " + strconv.Itoa(plan.CPU),
+		"ram: " + strconv.Itoa(plan.RAM),
+		"storage: " + strconv.Itoa(plan.Storage),
+		"price_monthly: " + formatMonthly(plan.PriceMonthly),
+		"price_hourly: " + formatHourly(plan.PriceHourly),
+	}
+
+	var b strings.Builder
+	for i, field := range fields {
+		if i == 0 {
+			b.WriteString(indent[:len(indent)-2] + "- ")
+		} else {
+			b.WriteString("\n" + indent)
+		}
+		b.WriteString(field)
+	}
+	return b.String()
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testPlansYAML = `# Test catalog
+version: "2024.12"
+last_updated: "2024-12-18"
+default_plan: "DEV-2xCPU-4GB"
+
+categories:
+  developer:
+    name: "Developer Plans"
+    icon: "🚀"
+    plans:
+      - id: "DEV-2xCPU-4GB"
+        display_name: "Standard Dev"
+        cpu: 2
+        ram: 4096
+        storage: 60
+        price_monthly: 18.00
+        price_hourly: 0.025
+        use_cases:
+          - "Web development"
+
+      - id: "DEV-1xCPU-1GB"
+        display_name: "Micro Dev"
+        cpu: 1
+        ram: 1024
+        storage: 20
+        price_monthly: 4.50
+        price_hourly: 0.006
+
+  cloud_native:
+    name: "Cloud Native"
+    plans:
+      - id: "CN-2xCPU-4GB"
+        display_name: "Cloud Native Medium"
+        cpu: 2
+        ram: 4096
+        storage: 0  # Storage configured separately
+        price_monthly: 16.00
+        price_hourly: 0.022
+
+selection_rules:
+  recommendations:
+    default: "DEV-2xCPU-4GB"
+    by_workload:
+      microservices: "CN-2xCPU-4GB"
+`
+
+func TestCategoryForPlan(t *testing.T) {
+	tests := map[string]string{
+		"DEV-2xCPU-4GB":         "developer",
+		"CN-2xCPU-4GB":          "cloud_native",
+		"2xCPU-4GB":             "general_purpose",
+		"HICPU-8xCPU-12GB":      "high_cpu",
+		"HIMEM-4xCPU-32GB":      "high_memory",
+		"GPU-8xCPU-64GB-1xL40S": "",
+	}
+
+	for id, want := range tests {
+		if got := CategoryForPlan(id); got != want {
+			t.Errorf("CategoryForPlan(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	plans, err := ParseServerPlans([]byte(testPlansYAML))
+	if err != nil {
+		t.Fatalf("Failed to parse plans: %v", err)
+	}
+
+	diff := plans.Diff([]ServerPlan{
+		{ID: "DEV-2xCPU-4GB", CPU: 2, RAM: 4096, Storage: 60, PriceMonthly: 20, PriceHourly: 0.0298},
+		{ID: "CN-2xCPU-4GB", CPU: 2, RAM: 4096},
+		{ID: "DEV-2xCPU-8GB", CPU: 2, RAM: 8192, Storage: 80, PriceMonthly: 30, PriceHourly: 0.0446},
+		{ID: "GPU-8xCPU-64GB-1xL40S", CPU: 8, RAM: 65536, Storage: 100},
+	})
+
+	if len(diff.Added) != 2 || diff.Added[0].ID != "DEV-2xCPU-8GB" || diff.Added[0].Category != "developer" ||
+		diff.Added[1].ID != "GPU-8xCPU-64GB-1xL40S" || diff.Added[1].Category != "" {
+		t.Errorf("Unexpected added plans: %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].ID != "DEV-1xCPU-1GB" {
+		t.Errorf("Unexpected removed plans: %+v", diff.Removed)
+	}
+
+	// CN-2xCPU-4GB has no price in the zone, so only its specifications are compared
+	want := []PlanChange{{
+		ID:       "DEV-2xCPU-4GB",
+		Category: "developer",
+		Fields: []FieldChange{
+			{Field: "price_monthly", Old: "18.00", New: "20.00"},
+			{Field: "price_hourly", Old: "0.025", New: "0.0298"},
+		},
+	}}
+	for i := range diff.Changed {
+		diff.Changed[i].Plan = ServerPlan{}
+	}
+	if !reflect.DeepEqual(diff.Changed, want) {
+		t.Errorf("Changed = %+v, want %+v", diff.Changed, want)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	plans, err := ParseServerPlans([]byte(testPlansYAML))
+	if err != nil {
+		t.Fatalf("Failed to parse plans: %v", err)
+	}
+
+	want := []string{"default_plan", "selection_rules.recommendations.default"}
+	if got := plans.References("DEV-2xCPU-4GB"); !reflect.DeepEqual(got, want) {
+		t.Errorf("References(DEV-2xCPU-4GB) = %v, want %v", got, want)
+	}
+	want = []string{"selection_rules.recommendations.by_workload.microservices"}
+	if got := plans.References("CN-2xCPU-4GB"); !reflect.DeepEqual(got, want) {
+		t.Errorf("References(CN-2xCPU-4GB) = %v, want %v", got, want)
+	}
+	if got := plans.References("DEV-1xCPU-1GB"); len(got) != 0 {
+		t.Errorf("References(DEV-1xCPU-1GB) = %v, want none", got)
+	}
+}
+
+func TestSyncPlansYAML(t *testing.T) {
+	diff := &PlanDiff{
+		Added: []PlanChange{
+			{
+				ID:       "CN-4xCPU-8GB",
+				Category: "cloud_native",
+				Plan:     ServerPlan{ID: "CN-4xCPU-8GB", CPU: 4, RAM: 8192, PriceMonthly: 32, PriceHourly: 0.0476},
+			},
+			{
+				ID:   "GPU-8xCPU-64GB-1xL40S",
+				Plan: ServerPlan{ID: "GPU-8xCPU-64GB-1xL40S", CPU: 8, RAM: 65536},
+			},
+		},
+		Removed: []PlanChange{{ID: "DEV-1xCPU-1GB", Category: "developer"}},
+		Changed: []PlanChange{
+			{ID: "DEV-2xCPU-4GB", Fields: []FieldChange{{Field: "price_hourly", Old: "0.025", New: "0.027"}}},
+			{ID: "CN-2xCPU-4GB", Fields: []FieldChange{{Field: "storage", Old: "0", New: "10"}}},
+		},
+	}
+
+	updated, err := SyncPlansYAML([]byte(testPlansYAML), diff, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("SyncPlansYAML() error = %v", err)
+	}
+
+	want := `# Test catalog
+version: "2026.10"
+last_updated: "2026-10-18"
+default_plan: "DEV-2xCPU-4GB"
+
+categories:
+  developer:
+    name: "Developer Plans"
+    icon: "🚀"
+    plans:
+      - id: "DEV-2xCPU-4GB"
+        display_name: "Standard Dev"
+        cpu: 2
+        ram: 4096
+        storage: 60
+        price_monthly: 18.00
+        price_hourly: 0.027
+        use_cases:
+          - "Web development"
+
+  cloud_native:
+    name: "Cloud Native"
+    plans:
+      - id: "CN-2xCPU-4GB"
+        display_name: "Cloud Native Medium"
+        cpu: 2
+        ram: 4096
+        storage: 10  # Storage configured separately
+        price_monthly: 16.00
+        price_hourly: 0.022
+
+      - id: "CN-4xCPU-8GB"
+        display_name: "CN-4xCPU-8GB"
+        description: "4 CPU, 8 GB RAM"
+        cpu: 4
+        ram: 8192
+        storage: 0
+        price_monthly: 32.00
+        price_hourly: 0.0476
+
+selection_rules:
+  recommendations:
+    default: "DEV-2xCPU-4GB"
+    by_workload:
+      microservices: "CN-2xCPU-4GB"
+`
+	if string(updated) != want {
+		t.Errorf("SyncPlansYAML() =\n%s\nwant\n%s", updated, want)
+	}
+
+	if _, err := ParseServerPlans(updated); err != nil {
+		t.Errorf("Updated plans do not parse: %v", err)
+	}
+}
+
+func TestFormatHourly(t *testing.T) {
+	tests := map[float32]string{
+		0.025:   "0.025",
+		0.1:     "0.100",
+		0.0035:  "0.0035",
+		0.0056:  "0.0056",
+		0.02976: "0.0298",
+	}
+
+	for price, want := range tests {
+		if got := formatHourly(price); got != want {
+			t.Errorf("formatHourly(%v) = %q, want %q", price, got, want)
+		}
+	}
+}
+
+func TestSyncPlansYAMLShallowIndentation(t *testing.T) {
+	plans := `categories:
+ developer:
+  plans:
+  - id: "DEV-2xCPU-4GB"
+    cpu: 2
+    ram: 4096
+`
+	diff := &PlanDiff{Added: []PlanChange{{
+		ID:       "DEV-1xCPU-2GB",
+		Category: "developer",
+		Plan:     ServerPlan{ID: "DEV-1xCPU-2GB", CPU: 1, RAM: 2048, PriceMonthly: 8, PriceHourly: 0.0119},
+	}}}
+
+	updated, err := SyncPlansYAML([]byte(plans), diff, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("SyncPlansYAML() error = %v", err)
+	}
+
+	parsed, err := ParseServerPlans(updated)
+	if err != nil {
+		t.Fatalf("Updated plans do not parse: %v\n%s", err, updated)
+	}
+	if plan, _, err := parsed.GetPlanByID("DEV-1xCPU-2GB"); err != nil || plan.RAM != 2048 {
+		t.Errorf("Added plan not found in\n%s", updated)
+	}
+}
+
+func TestSyncPlansYAMLRejectsFlowSequences(t *testing.T) {
+	plans := `categories:
+  developer:
+    plans: [{id: "DEV-2xCPU-4GB", cpu: 2, ram: 4096}]
+`
+	diff := &PlanDiff{Added: []PlanChange{{ID: "DEV-1xCPU-2GB", Category: "developer"}}}
+
+	if _, err := SyncPlansYAML([]byte(plans), diff, time.Now()); err == nil {
+		t.Error("Expected an error for a flow sequence of plans")
+	}
+}
@@ -3,6 +3,8 @@ package options
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type Options struct {
@@ -16,6 +18,29 @@ type Options struct {
 	Template string
 	Username string
 	Password string
+
+	StorageEncryption bool
+
+	// UserData is user-supplied cloud-init user data, inline or a file path
+	UserData string
+
+	// DockerInstall is the Docker install source and DockerVersion the pinned version
+	DockerInstall string
+	DockerVersion string
+
+	// Docker daemon settings written to /etc/docker/daemon.json
+	DockerRegistryMirrors    []string
+	DockerInsecureRegistries []string
+	DockerLogDriver          string
+	DockerLogMaxSize         string
+	DockerLogMaxFile         string
+	DockerDataRoot           string
+	DockerAddressPools       []string
+
+	// SwapSize is "auto", "off" or a size such as 2G
+	SwapSize      string
+	SysctlProfile string
+	Sysctl        []string
 }
 
 func FromEnv(skipMachine bool) (*Options, error) {
@@ -64,6 +89,26 @@ func FromEnv(skipMachine bool) (*Options, error) {
 	// Template is optional, so use fromEnv instead of fromEnvOrError
 	retOptions.Template = os.Getenv("UPCLOUD_TEMPLATE")
 
+	retOptions.StorageEncryption, err = fromEnvBool("UPCLOUD_STORAGE_ENCRYPTION")
+	if err != nil {
+		return nil, err
+	}
+	retOptions.UserData = os.Getenv("UPCLOUD_USER_DATA")
+
+	retOptions.DockerInstall = os.Getenv("UPCLOUD_DOCKER_INSTALL")
+	retOptions.DockerVersion = os.Getenv("UPCLOUD_DOCKER_VERSION")
+	retOptions.DockerRegistryMirrors = fromEnvList("UPCLOUD_DOCKER_REGISTRY_MIRRORS")
+	retOptions.DockerInsecureRegistries = fromEnvList("UPCLOUD_DOCKER_INSECURE_REGISTRIES")
+	retOptions.DockerLogDriver = os.Getenv("UPCLOUD_DOCKER_LOG_DRIVER")
+	retOptions.DockerLogMaxSize = os.Getenv("UPCLOUD_DOCKER_LOG_MAX_SIZE")
+	retOptions.DockerLogMaxFile = os.Getenv("UPCLOUD_DOCKER_LOG_MAX_FILE")
+	retOptions.DockerDataRoot = os.Getenv("UPCLOUD_DOCKER_DATA_ROOT")
+	retOptions.DockerAddressPools = fromEnvList("UPCLOUD_DOCKER_ADDRESS_POOLS")
+
+	retOptions.SwapSize = os.Getenv("UPCLOUD_SWAP_SIZE")
+	retOptions.SysctlProfile = os.Getenv("UPCLOUD_SYSCTL_PROFILE")
+	retOptions.Sysctl = fromEnvList("UPCLOUD_SYSCTL")
+
 	return retOptions, nil
 }
 
@@ -110,3 +155,29 @@ func fromEnvOrError(name string) (string, error) {
 
 	return val, nil
 }
+
+func fromEnvBool(name string) (bool, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return false, nil
+	}
+
+	ret, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q for option %s, expected true or false", val, name)
+	}
+
+	return ret, nil
+}
+
+// fromEnvList reads a comma separated option, dropping empty entries
+func fromEnvList(name string) []string {
+	var ret []string
+	for _, val := range strings.Split(os.Getenv(name), ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			ret = append(ret, val)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,278 @@
+// Package project detects the languages, frameworks and workloads of a
+// project from its manifest files, for plan recommendations
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/jsonc"
+)
+
+// maxDepth is how many directory levels below the project root are scanned,
+// so the parts of a monorepo are found
+const maxDepth = 2
+
+// skippedDirs are dependency and build directories never scanned
+var skippedDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+	"dist":         true,
+	"build":        true,
+}
+
+// Stack is a technology detected in a project
+type Stack struct {
+	Language  string `json:"language,omitempty" yaml:"language,omitempty"`
+	Framework string `json:"framework,omitempty" yaml:"framework,omitempty"`
+	Workload  string `json:"workload,omitempty" yaml:"workload,omitempty"`
+	// Source is the file the stack was detected in, relative to the project
+	Source string `json:"source" yaml:"source"`
+}
+
+// String describes the stack for humans
+func (s Stack) String() string {
+	var parts []string
+	for _, part := range []string{s.Language, s.Framework} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if s.Workload != "" {
+		parts = append(parts, s.Workload+" workload")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// detector detects stacks from the content of a manifest file
+type detector func(dir string, content []byte) []Stack
+
+// detectors maps manifest file names to their detectors
+var detectors = map[string]detector{
+	"devcontainer.json":  detectDevContainer,
+	".devcontainer.json": detectDevContainer,
+	"package.json":       detectPackageJSON,
+	"go.mod":             single(Stack{Language: "go"}),
+	"Cargo.toml":         single(Stack{Language: "rust"}),
+	"CMakeLists.txt":     single(Stack{Language: "cpp"}),
+	"pom.xml":            detectJava,
+	"build.gradle":       detectJava,
+	"build.gradle.kts":   detectJava,
+	"requirements.txt":   detectPython,
+	"pyproject.toml":     detectPython,
+}
+
+// Detect scans a project directory for manifest files and returns the stacks
+// found in them, in file order
+func Detect(dir string) ([]Stack, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	var stacks []Stack
+	err = filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if rel == "." {
+				return nil
+			}
+			name := entry.Name()
+			if skippedDirs[name] || (strings.HasPrefix(name, ".") && name != ".devcontainer") || strings.Count(rel, "/") >= maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		detect, ok := detectors[entry.Name()]
+		if !ok {
+			// .NET projects are named after the project
+			if path.Ext(entry.Name()) != ".csproj" {
+				return nil
+			}
+			detect = single(Stack{Framework: "dotnet"})
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		for _, stack := range detect(filepath.Dir(file), content) {
+			stack.Source = rel
+			stacks = append(stacks, stack)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stacks, nil
+}
+
+// single returns a detector that always detects the same stack
+func single(stack Stack) detector {
+	return func(string, []byte) []Stack {
+		return []Stack{stack}
+	}
+}
+
+// devContainerImage matches the official Dev Container images and features
+var devContainerImage = regexp.MustCompile(`^(?:mcr\.microsoft\.com/(?:vscode/)?devcontainers|ghcr\.io/devcontainers/features)/([a-z-]+)`)
+
+// devContainerStacks maps Dev Container image and feature names to stacks
+var devContainerStacks = map[string]Stack{
+	"javascript-node":          {Language: "javascript"},
+	"typescript-node":          {Language: "typescript"},
+	"node":                     {Language: "javascript"},
+	"python":                   {Language: "python"},
+	"go":                       {Language: "go"},
+	"rust":                     {Language: "rust"},
+	"java":                     {Language: "java"},
+	"cpp":                      {Language: "cpp"},
+	"dotnet":                   {Framework: "dotnet"},
+	"docker-in-docker":         {Workload: "containers"},
+	"docker-outside-of-docker": {Workload: "containers"},
+	"anaconda":                 {Language: "python", Workload: "data_science"},
+	"miniconda":                {Language: "python", Workload: "data_science"},
+}
+
+// detectDevContainer detects stacks from the image and features of a
+// devcontainer.json, which may contain comments and trailing commas
+func detectDevContainer(_ string, content []byte) []Stack {
+	var devContainer struct {
+		Image    string                     `json:"image"`
+		Features map[string]json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(jsonc.ToJSON(content), &devContainer); err != nil {
+		return nil
+	}
+
+	features := make([]string, 0, len(devContainer.Features))
+	for feature := range devContainer.Features {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	references := append([]string{devContainer.Image}, features...)
+
+	var stacks []Stack
+	for _, reference := range references {
+		match := devContainerImage.FindStringSubmatch(reference)
+		if match == nil {
+			continue
+		}
+		if stack, ok := devContainerStacks[match[1]]; ok {
+			stacks = append(stacks, stack)
+		}
+	}
+	return stacks
+}
+
+// packageFrameworks maps npm packages to frameworks, checked in order
+var packageFrameworks = []struct {
+	pkg       string
+	framework string
+}{
+	{"@angular/core", "angular"},
+	{"vue", "vue"},
+	{"nuxt", "vue"},
+	{"react", "react"},
+	{"next", "react"},
+}
+
+// detectPackageJSON detects JavaScript or TypeScript and the frontend framework
+func detectPackageJSON(dir string, content []byte) []Stack {
+	var manifest struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil
+	}
+
+	has := func(pkg string) bool {
+		_, dep := manifest.Dependencies[pkg]
+		_, devDep := manifest.DevDependencies[pkg]
+		return dep || devDep
+	}
+
+	stack := Stack{Language: "javascript"}
+	if _, err := os.Stat(filepath.Join(dir, "tsconfig.json")); err == nil || has("typescript") {
+		stack.Language = "typescript"
+	}
+	for _, candidate := range packageFrameworks {
+		if has(candidate.pkg) {
+			stack.Framework = candidate.framework
+			break
+		}
+	}
+	return []Stack{stack}
+}
+
+// detectJava detects Java and Spring from Maven and Gradle builds
+func detectJava(_ string, content []byte) []Stack {
+	stack := Stack{Language: "java"}
+	if strings.Contains(string(content), "org.springframework") {
+		stack.Framework = "spring"
+	}
+	return []Stack{stack}
+}
+
+// Python packages implying a framework or workload
+var (
+	pythonMLPackages   = []string{"torch", "tensorflow", "keras", "jax", "scikit-learn", "transformers"}
+	pythonDataPackages = []string{"pandas", "polars", "jupyter", "jupyterlab", "notebook"}
+	// Requirement lines, Poetry keys and quoted PEP 621 dependencies
+	pythonPackageName = regexp.MustCompile(`(?m)^\s*([A-Za-z0-9][A-Za-z0-9._-]*)|"([A-Za-z0-9][A-Za-z0-9._-]*)\s*(?:[<>=!~;\[,]|")`)
+)
+
+// detectPython detects Python, Django and machine learning or data science
+// workloads from requirements.txt or pyproject.toml
+func detectPython(_ string, content []byte) []Stack {
+	packages := map[string]bool{}
+	for _, match := range pythonPackageName.FindAllStringSubmatch(string(content), -1) {
+		name := match[1] + match[2]
+		packages[strings.ToLower(strings.ReplaceAll(name, "_", "-"))] = true
+	}
+
+	hasAny := func(names []string) bool {
+		for _, name := range names {
+			if packages[name] {
+				return true
+			}
+		}
+		return false
+	}
+
+	stack := Stack{Language: "python"}
+	if packages["django"] {
+		stack.Framework = "django"
+	}
+	switch {
+	case hasAny(pythonMLPackages):
+		stack.Workload = "ml_development"
+	case hasAny(pythonDataPackages):
+		stack.Workload = "data_science"
+	}
+	return []Stack{stack}
+}
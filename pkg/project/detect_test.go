@@ -0,0 +1,115 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []Stack
+	}{
+		{
+			name:  "Go module",
+			files: map[string]string{"go.mod": "module example.com/app\n"},
+			want:  []Stack{{Language: "go", Source: "go.mod"}},
+		},
+		{
+			name: "TypeScript with React",
+			files: map[string]string{
+				"package.json":  `{"dependencies": {"react": "^18.0.0"}, "devDependencies": {"typescript": "^5.0.0"}}`,
+				"tsconfig.json": `{}`,
+			},
+			want: []Stack{{Language: "typescript", Framework: "react", Source: "package.json"}},
+		},
+		{
+			name:  "JavaScript with Angular",
+			files: map[string]string{"package.json": `{"dependencies": {"@angular/core": "17", "react": "18"}}`},
+			want:  []Stack{{Language: "javascript", Framework: "angular", Source: "package.json"}},
+		},
+		{
+			name:  "Spring with Maven",
+			files: map[string]string{"pom.xml": "<project><parent><groupId>org.springframework.boot</groupId></parent></project>"},
+			want:  []Stack{{Language: "java", Framework: "spring", Source: "pom.xml"}},
+		},
+		{
+			name:  "Django requirements",
+			files: map[string]string{"requirements.txt": "# web\nDjango>=4.2\npsycopg2-binary==2.9\n"},
+			want:  []Stack{{Language: "python", Framework: "django", Source: "requirements.txt"}},
+		},
+		{
+			name:  "Machine learning pyproject",
+			files: map[string]string{"pyproject.toml": "[project]\nname = \"model\"\ndependencies = [\"torch>=2\", \"pandas\"]\n"},
+			want:  []Stack{{Language: "python", Workload: "ml_development", Source: "pyproject.toml"}},
+		},
+		{
+			name: "Dev Container with comments and features",
+			files: map[string]string{".devcontainer/devcontainer.json": `{
+				// Rust toolchain
+				"image": "mcr.microsoft.com/devcontainers/rust:1",
+				"features": {
+					"ghcr.io/devcontainers/features/docker-in-docker:2": {},
+				},
+			}`},
+			want: []Stack{
+				{Language: "rust", Source: ".devcontainer/devcontainer.json"},
+				{Workload: "containers", Source: ".devcontainer/devcontainer.json"},
+			},
+		},
+		{
+			name: "Monorepo",
+			files: map[string]string{
+				"backend/go.mod":                 "module example.com/backend\n",
+				"frontend/package.json":          `{"dependencies": {"vue": "3"}}`,
+				"frontend/node_modules/x/go.mod": "module ignored\n",
+				"a/b/c/Cargo.toml":               "[package]\n",
+			},
+			want: []Stack{
+				{Language: "go", Source: "backend/go.mod"},
+				{Language: "javascript", Framework: "vue", Source: "frontend/package.json"},
+			},
+		},
+		{
+			name:  "Nothing to detect",
+			files: map[string]string{"README.md": "# Hello\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				file := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := Detect(dir)
+			if err != nil {
+				t.Fatalf("Detect() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectNotADirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Detect(file); err == nil {
+		t.Error("Detect() of a file should fail")
+	}
+}
@@ -10,11 +10,25 @@ import (
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/client"
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
 	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/service"
+	"github.com/loft-sh/log"
 )
 
+// upcloudService is the part of the UpCloud API used by the client, so tests
+// can replace it with a fake
+type upcloudService interface {
+	service.Account
+	service.Cloud
+	service.IPAddress
+	service.Server
+	service.Storage
+
+	GetServersWithFilters(ctx context.Context, r *request.GetServersWithFiltersRequest) (*upcloud.Servers, error)
+}
+
 // Client represents the UpCloud API client
 type Client struct {
-	service *service.Service
+	service upcloudService
+	api     *client.Client
 	timeout time.Duration
 }
 
@@ -28,6 +42,36 @@ type ServerConfig struct {
 	Template string
 	SSHKey   string
 	UserData string
+	// ResolvedTemplate is the template already resolved and validated by the
+	// caller with ResolveTemplate. Preflight and Create use it as is instead
+	// of looking up Template or Image again.
+	ResolvedTemplate *TemplateInfo
+	// Owner is recorded in the server labels to tell whose workspace it is
+	Owner string
+
+	// Encrypted requests encryption at rest for all storage devices
+	Encrypted bool
+}
+
+// ServerInfo summarizes a DevPod-managed server
+type ServerInfo struct {
+	MachineID string        `json:"machine_id" yaml:"machine_id"`
+	UUID      string        `json:"uuid" yaml:"uuid"`
+	Hostname  string        `json:"hostname" yaml:"hostname"`
+	Status    string        `json:"status" yaml:"status"`
+	Plan      string        `json:"plan" yaml:"plan"`
+	Zone      string        `json:"zone" yaml:"zone"`
+	IP        string        `json:"ip,omitempty" yaml:"ip,omitempty"`
+	Storages  []StorageInfo `json:"storages" yaml:"storages"`
+}
+
+// StorageInfo summarizes a storage device attached to a server
+type StorageInfo struct {
+	UUID      string `json:"uuid" yaml:"uuid"`
+	Title     string `json:"title" yaml:"title"`
+	Size      int    `json:"size_gb" yaml:"size_gb"`
+	Tier      string `json:"tier" yaml:"tier"`
+	Encrypted bool   `json:"encrypted" yaml:"encrypted"`
 }
 
 // NewUpCloud creates a new UpCloud client
@@ -49,6 +93,7 @@ func NewUpCloud(username, password string) *Client {
 
 	return &Client{
 		service: svc,
+		api:     httpClient,
 		timeout: time.Duration(DefaultTimeout) * time.Second,
 	}
 }
@@ -96,17 +141,31 @@ func (c *Client) Create(ctx context.Context, config *ServerConfig) error {
 		return WrapError(err, "plan mapping")
 	}
 
-	// Use custom template if provided, otherwise map image to template UUID
+	// Use the resolved template if provided, otherwise the custom template or
+	// the template the image maps to
 	var templateUUID string
-	if config.Template != "" {
-		templateUUID = config.Template
+	if config.ResolvedTemplate != nil {
+		templateUUID = config.ResolvedTemplate.UUID
+	} else if config.Template != "" {
+		template, err := c.ValidateTemplate(ctx, config.Template, config.Zone)
+		if err != nil {
+			return err
+		}
+		templateUUID = template.UUID
 	} else {
-		templateUUID, err = MapImageToTemplate(config.Image)
+		templateUUID, err = c.ResolveImage(ctx, config.Image)
 		if err != nil {
 			return WrapError(err, "image mapping")
 		}
 	}
 
+	// Encryption must be supported by the template
+	if config.Encrypted {
+		if err := c.validateEncryption(ctx, templateUUID); err != nil {
+			return err
+		}
+	}
+
 	// Parse storage size
 	storageSize, err := ParseStorageSize(config.Storage)
 	if err != nil {
@@ -128,11 +187,12 @@ func (c *Client) Create(ctx context.Context, config *ServerConfig) error {
 		// Configure storage
 		StorageDevices: []request.CreateServerStorageDevice{
 			{
-				Action:  request.CreateServerStorageDeviceActionClone,
-				Storage: templateUUID,
-				Title:   "root",
-				Size:    storageSize,
-				Tier:    GetStorageTier(plan),
+				Action:    request.CreateServerStorageDeviceActionClone,
+				Storage:   templateUUID,
+				Title:     "root",
+				Size:      storageSize,
+				Tier:      GetStorageTier(plan),
+				Encrypted: upcloud.FromBool(config.Encrypted),
 			},
 		},
 
@@ -159,6 +219,19 @@ func (c *Client) Create(ctx context.Context, config *ServerConfig) error {
 		},
 	}
 
+	// Label the server so list and prune can find it
+	now := time.Now().UTC().Format(time.RFC3339)
+	labels := upcloud.LabelSlice{
+		{Key: LabelManaged, Value: "true"},
+		{Key: LabelMachineID, Value: config.Hostname},
+		{Key: LabelCreatedAt, Value: now},
+		{Key: LabelStartedAt, Value: now},
+	}
+	if config.Owner != "" {
+		labels = append(labels, upcloud.Label{Key: LabelOwner, Value: config.Owner})
+	}
+	createReq.Labels = &labels
+
 	// Add SSH key if provided
 	if config.SSHKey != "" {
 		createReq.LoginUser = &request.LoginUser{
@@ -187,18 +260,60 @@ func (c *Client) Create(ctx context.Context, config *ServerConfig) error {
 
 	_, err = c.service.WaitForServerState(ctx, waitReq)
 	if err != nil {
-		// If waiting fails, try to clean up by deleting the server
-		_ = c.service.DeleteServer(ctx, &request.DeleteServerRequest{
-			UUID: serverDetails.UUID,
-		})
+		c.rollbackCreate(ctx, serverDetails.UUID)
 		return WrapError(err, "waiting for server to start")
 	}
 
+	// Storages cannot be labelled at creation; the label lets prune find
+	// the disk if the server is deleted without it
+	if rootStorage, err := FindRootStorage(serverDetails); err == nil {
+		storageLabels := []upcloud.Label{
+			{Key: LabelManaged, Value: "true"},
+			{Key: LabelMachineID, Value: config.Hostname},
+		}
+		if config.Owner != "" {
+			storageLabels = append(storageLabels, upcloud.Label{Key: LabelOwner, Value: config.Owner})
+		}
+		_, _ = c.service.ModifyStorage(ctx, &request.ModifyStorageRequest{
+			UUID:   rootStorage.UUID,
+			Labels: &storageLabels,
+		})
+	}
+
 	return nil
 }
 
+// rollbackCreate deletes a server that failed to start together with its
+// storages. It runs on a fresh context, as the create's may have timed out.
+func (c *Client) rollbackCreate(ctx context.Context, uuid string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	// A server still starting can neither be stopped nor deleted
+	details, err := c.service.WaitForServerState(ctx, &request.WaitForServerStateRequest{
+		UUID:           uuid,
+		UndesiredState: upcloud.ServerStateMaintenance,
+	})
+	if err == nil {
+		err = c.removeServer(ctx, uuid, details.State, true)
+	}
+	if err != nil {
+		log.Default.Warnf("Could not delete server %s after the failed create, delete it and its storages manually: %v", uuid, err)
+	}
+}
+
 // Delete deletes a server
 func (c *Client) Delete(ctx context.Context, serverID string) error {
+	return c.deleteServer(ctx, serverID, false)
+}
+
+// DeleteWithStorages deletes a server together with its attached storages
+func (c *Client) DeleteWithStorages(ctx context.Context, serverID string) error {
+	return c.deleteServer(ctx, serverID, true)
+}
+
+// deleteServer stops and deletes a server, optionally including its storages
+func (c *Client) deleteServer(ctx context.Context, serverID string, withStorages bool) error {
 	// Check for test mode
 	if c.service == nil {
 		fmt.Fprintf(os.Stderr, "Test mode: Simulating server deletion for %s\n", serverID)
@@ -217,10 +332,17 @@ func (c *Client) Delete(ctx context.Context, serverID string) error {
 		return err
 	}
 
+	return c.removeServer(ctx, server.UUID, server.State, withStorages)
+}
+
+// removeServer hard stops a server if needed and deletes it by UUID
+func (c *Client) removeServer(ctx context.Context, uuid, state string, withStorages bool) error {
+	var err error
+
 	// Stop the server first if it's running
-	if server.State == upcloud.ServerStateStarted {
+	if state == upcloud.ServerStateStarted {
 		stopReq := &request.StopServerRequest{
-			UUID:     server.UUID,
+			UUID:     uuid,
 			StopType: request.ServerStopTypeHard,
 		}
 		_, err = c.service.StopServer(ctx, stopReq)
@@ -230,17 +352,23 @@ func (c *Client) Delete(ctx context.Context, serverID string) error {
 
 		// Wait for server to stop
 		waitReq := &request.WaitForServerStateRequest{
-			UUID:         server.UUID,
+			UUID:         uuid,
 			DesiredState: upcloud.ServerStateStopped,
 		}
 		_, _ = c.service.WaitForServerState(ctx, waitReq)
 	}
 
 	// Delete the server
-	deleteReq := &request.DeleteServerRequest{
-		UUID: server.UUID,
+	if withStorages {
+		err = c.service.DeleteServerAndStorages(ctx, &request.DeleteServerAndStoragesRequest{
+			UUID:    uuid,
+			Backups: request.DeleteStorageBackupsModeDelete,
+		})
+	} else {
+		err = c.service.DeleteServer(ctx, &request.DeleteServerRequest{
+			UUID: uuid,
+		})
 	}
-	err = c.service.DeleteServer(ctx, deleteReq)
 	if err != nil && !IsNotFoundError(err) {
 		return WrapError(err, "server deletion")
 	}
@@ -269,26 +397,7 @@ func (c *Client) Start(ctx context.Context, serverID string) error {
 		return nil
 	}
 
-	// Start the server
-	startReq := &request.StartServerRequest{
-		UUID: server.UUID,
-	}
-	_, err = c.service.StartServer(ctx, startReq)
-	if err != nil {
-		return WrapError(err, "server start")
-	}
-
-	// Wait for server to start
-	waitReq := &request.WaitForServerStateRequest{
-		UUID:         server.UUID,
-		DesiredState: upcloud.ServerStateStarted,
-	}
-	_, err = c.service.WaitForServerState(ctx, waitReq)
-	if err != nil {
-		return WrapError(err, "waiting for server to start")
-	}
-
-	return nil
+	return c.startServer(ctx, server.UUID)
 }
 
 // Stop stops a running server
@@ -309,30 +418,14 @@ func (c *Client) Stop(ctx context.Context, serverID string) error {
 
 	// Check if already stopped
 	if server.State == upcloud.ServerStateStopped {
+		// Stopped from inside the guest, accrued costs are best effort
+		if err := c.closeRun(ctx, server.UUID); err != nil {
+			log.Default.Debugf("Could not record the stop of server %s: %v", server.UUID, err)
+		}
 		return nil
 	}
 
-	// Stop the server
-	stopReq := &request.StopServerRequest{
-		UUID:     server.UUID,
-		StopType: request.ServerStopTypeSoft,
-	}
-	_, err = c.service.StopServer(ctx, stopReq)
-	if err != nil {
-		return WrapError(err, "server stop")
-	}
-
-	// Wait for server to stop
-	waitReq := &request.WaitForServerStateRequest{
-		UUID:         server.UUID,
-		DesiredState: upcloud.ServerStateStopped,
-	}
-	_, err = c.service.WaitForServerState(ctx, waitReq)
-	if err != nil {
-		return WrapError(err, "waiting for server to stop")
-	}
-
-	return nil
+	return c.stopServer(ctx, server.UUID)
 }
 
 // Status returns the status of a server
@@ -392,6 +485,128 @@ func (c *Client) GetServerIP(ctx context.Context, serverID string) (string, erro
 	return ip, nil
 }
 
+// Describe returns details of a server, including its storage devices
+func (c *Client) Describe(ctx context.Context, serverID string) (*ServerInfo, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating server details for %s\n", serverID)
+		return &ServerInfo{
+			MachineID: serverID,
+			UUID:      "test-uuid",
+			Hostname:  GenerateHostname(serverID),
+			Status:    StatusRunning,
+			IP:        "192.0.2.1",
+		}, nil
+	}
+
+	// Find the server by machine ID
+	server, err := c.findServerByMachineID(ctx, serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	serverDetails, err := c.service.GetServerDetails(ctx, &request.GetServerDetailsRequest{
+		UUID: server.UUID,
+	})
+	if err != nil {
+		return nil, WrapError(err, "getting server details")
+	}
+
+	return NewServerInfo(serverDetails), nil
+}
+
+// ChangePlan moves a server to a different plan. A running server is stopped
+// for the change and started again; if it fails to start on the new plan, the
+// previous plan is restored.
+func (c *Client) ChangePlan(ctx context.Context, serverID string, plan string) error {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating plan change for %s to %s\n", serverID, plan)
+		return nil
+	}
+
+	// Find the server by machine ID
+	server, err := c.findServerByMachineID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+
+	if server.Plan == plan {
+		return nil
+	}
+
+	// The root storage must be usable with the new plan
+	_, rootStorage, err := c.getRootStorage(ctx, server)
+	if err != nil {
+		return err
+	}
+	if !IsStorageTierCompatible(plan, rootStorage.Tier) {
+		return &ProviderError{
+			Type: ErrorTypeInvalidParameter,
+			Message: fmt.Sprintf("Plan %s requires %s storage, but the root storage uses %s",
+				plan, GetStorageTier(plan), rootStorage.Tier),
+		}
+	}
+
+	previousPlan := server.Plan
+	wasRunning := server.State == upcloud.ServerStateStarted
+
+	// Plans can only be changed while the server is stopped
+	if wasRunning {
+		if err := c.stopServer(ctx, server.UUID); err != nil {
+			return err
+		}
+	}
+
+	err = c.modifyPlan(ctx, server.UUID, plan)
+	if err != nil {
+		if wasRunning {
+			_ = c.startServer(ctx, server.UUID)
+		}
+		return err
+	}
+
+	if !wasRunning {
+		return nil
+	}
+
+	err = c.startServer(ctx, server.UUID)
+	if err == nil {
+		return nil
+	}
+
+	// Roll back to the previous plan so the workspace stays usable
+	_, _ = c.service.StopServer(ctx, &request.StopServerRequest{
+		UUID:     server.UUID,
+		StopType: request.ServerStopTypeHard,
+	})
+	_, _ = c.service.WaitForServerState(ctx, &request.WaitForServerStateRequest{
+		UUID:         server.UUID,
+		DesiredState: upcloud.ServerStateStopped,
+	})
+	if rollbackErr := c.modifyPlan(ctx, server.UUID, previousPlan); rollbackErr != nil {
+		return fmt.Errorf("server failed to start on plan %s (%v) and rollback to %s failed: %w", plan, err, previousPlan, rollbackErr)
+	}
+	if restartErr := c.startServer(ctx, server.UUID); restartErr != nil {
+		return fmt.Errorf("server failed to start on plan %s (%v) and did not restart after rollback to %s: %w", plan, err, previousPlan, restartErr)
+	}
+
+	return fmt.Errorf("server failed to start on plan %s, rolled back to %s: %w", plan, previousPlan, err)
+}
+
+// modifyPlan changes the plan of a stopped server
+func (c *Client) modifyPlan(ctx context.Context, uuid, plan string) error {
+	_, err := c.service.ModifyServer(ctx, &request.ModifyServerRequest{
+		UUID: uuid,
+		Plan: plan,
+	})
+	if err != nil {
+		return WrapError(err, "plan change")
+	}
+
+	return nil
+}
+
 // findServerByMachineID is a helper to find a server by DevPod machine ID
 func (c *Client) findServerByMachineID(ctx context.Context, machineID string) (*upcloud.Server, error) {
 	// Check for test mode
@@ -421,3 +636,66 @@ func (c *Client) findServerByMachineID(ctx context.Context, machineID string) (*
 
 	return server, nil
 }
+
+// startServer starts a server by UUID and waits until it is running
+func (c *Client) startServer(ctx context.Context, uuid string) error {
+	startReq := &request.StartServerRequest{
+		UUID: uuid,
+	}
+	_, err := c.service.StartServer(ctx, startReq)
+	if err != nil {
+		return WrapError(err, "server start")
+	}
+
+	// Wait for server to start
+	waitReq := &request.WaitForServerStateRequest{
+		UUID:         uuid,
+		DesiredState: upcloud.ServerStateStarted,
+	}
+	_, err = c.service.WaitForServerState(ctx, waitReq)
+	if err != nil {
+		return WrapError(err, "waiting for server to start")
+	}
+
+	// Labels cannot be modified during the start's maintenance, uptime and
+	// accrued costs are best effort
+	if err := c.startRun(ctx, uuid); err != nil {
+		log.Default.Debugf("Could not record the start of server %s: %v", uuid, err)
+	}
+
+	return nil
+}
+
+// stopServer gracefully stops a server by UUID and waits until it is stopped
+func (c *Client) stopServer(ctx context.Context, uuid string) error {
+	stopReq := &request.StopServerRequest{
+		UUID:     uuid,
+		StopType: request.ServerStopTypeSoft,
+	}
+	_, err := c.service.StopServer(ctx, stopReq)
+	if err != nil {
+		// The server may already be shutting down, for example after the
+		// agent's inactivity stop powered it off from inside the guest
+		details, detailsErr := c.service.GetServerDetails(ctx, &request.GetServerDetailsRequest{UUID: uuid})
+		if detailsErr != nil || details.State == upcloud.ServerStateStarted {
+			return WrapError(err, "server stop")
+		}
+	}
+
+	// Wait for server to stop
+	waitReq := &request.WaitForServerStateRequest{
+		UUID:         uuid,
+		DesiredState: upcloud.ServerStateStopped,
+	}
+	_, err = c.service.WaitForServerState(ctx, waitReq)
+	if err != nil {
+		return WrapError(err, "waiting for server to stop")
+	}
+
+	// Accrued costs are best effort
+	if err := c.closeRun(ctx, uuid); err != nil {
+		log.Default.Debugf("Could not record the stop of server %s: %v", uuid, err)
+	}
+
+	return nil
+}
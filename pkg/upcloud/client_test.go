@@ -0,0 +1,52 @@
+package upcloud
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+)
+
+func TestCreateRollsBackServerThatFailsToStart(t *testing.T) {
+	fake := newFakeService()
+	fake.failNext("WaitForServerState", errors.New("timed out"))
+
+	err := fake.client().Create(context.Background(), &ServerConfig{
+		Hostname:         "devpod-image-builder",
+		Zone:             "de-fra1",
+		Plan:             "DEV-2xCPU-4GB",
+		Storage:          "50",
+		ResolvedTemplate: &TemplateInfo{UUID: TemplateUbuntu2404},
+	})
+	if err == nil {
+		t.Fatal("Create() error = nil, want the wait error")
+	}
+
+	if len(fake.servers) != 0 {
+		t.Errorf("servers = %v, want the failed server deleted", fake.servers)
+	}
+	if len(fake.storages) != 0 {
+		t.Errorf("storages = %v, want the failed server's disk deleted", fake.storages)
+	}
+}
+
+func TestCreateRollbackFailureIsNotFatal(t *testing.T) {
+	fake := newFakeService()
+	fake.failNext("WaitForServerState", errors.New("timed out"))
+	fake.failNext("StopServer", &upcloud.Problem{Type: "SERVER_STATE_ILLEGAL", Status: 409})
+
+	err := fake.client().Create(context.Background(), &ServerConfig{
+		Hostname:         "devpod-workspace",
+		Zone:             "de-fra1",
+		Plan:             "DEV-2xCPU-4GB",
+		Storage:          "50",
+		ResolvedTemplate: &TemplateInfo{UUID: TemplateUbuntu2404},
+	})
+	if err == nil {
+		t.Fatal("Create() error = nil, want the wait error")
+	}
+	if len(fake.servers) != 1 {
+		t.Errorf("servers = %v, want the server kept for manual cleanup", fake.servers)
+	}
+}
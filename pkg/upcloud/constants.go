@@ -35,6 +35,28 @@ const (
 	DefaultTimeout             = 300 // seconds
 )
 
+// Storage labels set on templates built by the provider
+const (
+	LabelOSFamily  = "devpod_os_family"
+	LabelOSVersion = "devpod_os_version"
+)
+
+// Server labels set on workspaces created by the provider. The root storage
+// gets the managed, machine ID and owner labels.
+const (
+	LabelManaged   = "devpod_managed"
+	LabelMachineID = "devpod_machine_id"
+	LabelOwner     = "devpod_owner"
+	LabelCreatedAt = "devpod_created_at"
+	LabelStartedAt = "devpod_started_at"
+	// LabelRunningHours sums the hours of completed runs for accrued costs
+	LabelRunningHours = "devpod_running_hours"
+)
+
+// ManagedServerPrefix starts the title of every server created by the
+// provider, including those created before servers were labelled
+const ManagedServerPrefix = "devpod-"
+
 // Plan mappings - Legacy mapping for backward compatibility
 // New plans are loaded from configs/server-plans.yaml
 var PlanMap = map[string]string{
@@ -65,7 +87,8 @@ var PlanMap = map[string]string{
 	"20xCPU-96GB": "20xCPU-96GB",
 }
 
-// OS Image name to template UUID mapping
+// OS Image name to template UUID mapping. Images are resolved against the
+// live template catalog; this map is only used when the API is unreachable.
 var ImageMap = map[string]string{
 	"Ubuntu Server 24.04 LTS (Noble Numbat)":    TemplateUbuntu2404,
 	"Ubuntu Server 22.04 LTS (Jammy Jellyfish)": TemplateUbuntu2204,
@@ -0,0 +1,109 @@
+package upcloud
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+)
+
+// CostEstimate breaks down what a workspace costs per hour in euro. Plans of
+// billed-when-on-only categories cost nothing while stopped; storage and IP
+// addresses are billed for as long as they exist.
+type CostEstimate struct {
+	Plan             string `json:"plan" yaml:"plan"`
+	Zone             string `json:"zone" yaml:"zone"`
+	BilledWhenOnOnly bool   `json:"billed_when_on_only" yaml:"billed_when_on_only"`
+
+	PlanHourly  float64 `json:"plan_hourly_eur" yaml:"plan_hourly_eur"`
+	PlanMonthly float64 `json:"plan_monthly_eur" yaml:"plan_monthly_eur"`
+
+	StorageSize int `json:"storage_gb" yaml:"storage_gb"`
+	// StorageBilled is the storage not included in the plan
+	StorageBilled int     `json:"storage_billed_gb" yaml:"storage_billed_gb"`
+	StorageHourly float64 `json:"storage_hourly_eur" yaml:"storage_hourly_eur"`
+
+	IPv4Hourly float64 `json:"ipv4_hourly_eur" yaml:"ipv4_hourly_eur"`
+
+	// PricesKnown is false when the zone's storage and IP prices could not be
+	// fetched, in which case only the plan is estimated
+	PricesKnown bool `json:"prices_known" yaml:"prices_known"`
+}
+
+// NewCostEstimate estimates the cost of a server of the given plan with the
+// given disks. Storage included in the plan is subtracted before pricing
+// the disks by their tier with the zone's prices.
+func NewCostEstimate(plan *config.ServerPlan, category *config.PlanCategory, zone string, disks []StorageInfo, prices ZonePrices) *CostEstimate {
+	estimate := &CostEstimate{
+		Plan:             plan.ID,
+		Zone:             zone,
+		BilledWhenOnOnly: category != nil && category.BilledWhenOnOnly,
+		PlanHourly:       euros(plan.PriceHourly),
+		PlanMonthly:      euros(plan.PriceMonthly),
+		PricesKnown:      len(prices) > 0,
+	}
+
+	included := plan.Storage
+	for _, disk := range disks {
+		estimate.StorageSize += disk.Size
+
+		billed := disk.Size - included
+		included = max(included-disk.Size, 0)
+		if billed <= 0 {
+			continue
+		}
+		estimate.StorageBilled += billed
+		estimate.StorageHourly += prices.MonthlyStorageCost(disk.Tier, billed) / HoursPerMonth
+	}
+
+	estimate.IPv4Hourly = prices.MonthlyIPv4Cost() / HoursPerMonth
+	return estimate
+}
+
+// RunningHourly returns the cost per hour while the server is running
+func (e *CostEstimate) RunningHourly() float64 {
+	return e.PlanHourly + e.StorageHourly + e.IPv4Hourly
+}
+
+// StoppedHourly returns the cost per hour while the server is stopped
+func (e *CostEstimate) StoppedHourly() float64 {
+	if e.BilledWhenOnOnly {
+		return e.StorageHourly + e.IPv4Hourly
+	}
+	return e.RunningHourly()
+}
+
+// Monthly returns the cost of a month with the given running hours. The plan
+// is capped at its monthly price.
+func (e *CostEstimate) Monthly(runningHours float64) float64 {
+	runningHours = math.Min(runningHours, HoursPerMonth)
+
+	planHours := float64(HoursPerMonth)
+	if e.BilledWhenOnOnly {
+		planHours = runningHours
+	}
+	plan := e.PlanHourly * planHours
+	if e.PlanMonthly > 0 {
+		plan = math.Min(plan, e.PlanMonthly)
+	}
+
+	return plan + (e.StorageHourly+e.IPv4Hourly)*HoursPerMonth
+}
+
+// Accrued returns the cost of a server that has existed for age and been
+// running for runningHours of it
+func (e *CostEstimate) Accrued(age time.Duration, runningHours float64) float64 {
+	hours := age.Hours()
+	planHours := hours
+	if e.BilledWhenOnOnly {
+		planHours = math.Min(runningHours, hours)
+	}
+	return e.PlanHourly*planHours + (e.StorageHourly+e.IPv4Hourly)*hours
+}
+
+// euros converts a catalog price without float32 rounding artefacts
+func euros(price float32) float64 {
+	value, _ := strconv.ParseFloat(strconv.FormatFloat(float64(price), 'f', -1, 32), 64)
+	return value
+}
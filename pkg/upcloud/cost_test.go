@@ -0,0 +1,108 @@
+package upcloud
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+)
+
+func TestCostEstimate(t *testing.T) {
+	// 1 EUR per GB-month for standard storage and 3 EUR per month for IPv4
+	prices := ZonePrices{
+		"storage_standard": {Amount: 1, Price: 100.0 / HoursPerMonth},
+		"ipv4_address":     {Amount: 1, Price: 300.0 / HoursPerMonth},
+	}
+	dev := &config.ServerPlan{ID: "DEV-2xCPU-4GB", Storage: 30, PriceHourly: 0.025, PriceMonthly: 15}
+	cloudNative := &config.ServerPlan{ID: "CN-2xCPU-4GB", PriceHourly: 0.03, PriceMonthly: 20}
+	billedWhenOn := &config.PlanCategory{BilledWhenOnOnly: true}
+
+	tests := []struct {
+		name          string
+		plan          *config.ServerPlan
+		category      *config.PlanCategory
+		disks         []StorageInfo
+		prices        ZonePrices
+		wantBilled    int
+		wantMonthly   float64 // at 100 running hours
+		wantStopped   float64 // per hour
+		wantAccrued   float64 // after 200 hours with 100 running
+		wantKnownCost bool
+	}{
+		{
+			name:          "Storage within the plan",
+			plan:          dev,
+			disks:         []StorageInfo{{Size: 30, Tier: "standard"}},
+			prices:        prices,
+			wantMonthly:   15 + 3,
+			wantStopped:   0.025 + 3.0/HoursPerMonth,
+			wantAccrued:   0.025*200 + 3.0/HoursPerMonth*200,
+			wantKnownCost: true,
+		},
+		{
+			name:          "Storage beyond the plan",
+			plan:          dev,
+			disks:         []StorageInfo{{Size: 50, Tier: "standard"}},
+			prices:        prices,
+			wantBilled:    20,
+			wantMonthly:   15 + 20 + 3,
+			wantStopped:   0.025 + 23.0/HoursPerMonth,
+			wantAccrued:   0.025*200 + 23.0/HoursPerMonth*200,
+			wantKnownCost: true,
+		},
+		{
+			name:          "Billed when on only",
+			plan:          cloudNative,
+			category:      billedWhenOn,
+			disks:         []StorageInfo{{Size: 50, Tier: "standard"}},
+			prices:        prices,
+			wantBilled:    50,
+			wantMonthly:   0.03*100 + 50 + 3,
+			wantStopped:   53.0 / HoursPerMonth,
+			wantAccrued:   0.03*100 + 53.0/HoursPerMonth*200,
+			wantKnownCost: true,
+		},
+		{
+			name:        "No price list",
+			plan:        cloudNative,
+			category:    billedWhenOn,
+			disks:       []StorageInfo{{Size: 50, Tier: "standard"}},
+			wantBilled:  50,
+			wantMonthly: 0.03 * 100,
+			wantStopped: 0,
+			wantAccrued: 0.03 * 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimate := NewCostEstimate(tt.plan, tt.category, "de-fra1", tt.disks, tt.prices)
+
+			if estimate.StorageBilled != tt.wantBilled {
+				t.Errorf("StorageBilled = %d, want %d", estimate.StorageBilled, tt.wantBilled)
+			}
+			if estimate.PricesKnown != tt.wantKnownCost {
+				t.Errorf("PricesKnown = %v, want %v", estimate.PricesKnown, tt.wantKnownCost)
+			}
+			if got := estimate.Monthly(100); math.Abs(got-tt.wantMonthly) > 0.001 {
+				t.Errorf("Monthly(100) = %.4f, want %.4f", got, tt.wantMonthly)
+			}
+			if got := estimate.StoppedHourly(); math.Abs(got-tt.wantStopped) > 0.0001 {
+				t.Errorf("StoppedHourly() = %.4f, want %.4f", got, tt.wantStopped)
+			}
+			if got := estimate.Accrued(200*time.Hour, 100); math.Abs(got-tt.wantAccrued) > 0.001 {
+				t.Errorf("Accrued() = %.4f, want %.4f", got, tt.wantAccrued)
+			}
+		})
+	}
+}
+
+func TestCostEstimateMonthlyCap(t *testing.T) {
+	plan := &config.ServerPlan{ID: "DEV-1xCPU-2GB", Storage: 30, PriceHourly: 0.011, PriceMonthly: 7}
+
+	estimate := NewCostEstimate(plan, nil, "de-fra1", nil, nil)
+	if got := estimate.Monthly(HoursPerMonth); got != 7 {
+		t.Errorf("Monthly() = %.4f, want the plan's monthly price of 7", got)
+	}
+}
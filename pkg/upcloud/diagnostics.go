@@ -0,0 +1,442 @@
+package upcloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/client"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+)
+
+// DiagnosticStatus is the outcome of a diagnostic check
+type DiagnosticStatus string
+
+// Diagnostic statuses
+const (
+	DiagnosticPass DiagnosticStatus = "pass"
+	DiagnosticWarn DiagnosticStatus = "warn"
+	DiagnosticFail DiagnosticStatus = "fail"
+	DiagnosticSkip DiagnosticStatus = "skip"
+)
+
+// Clock skew thresholds
+const (
+	// ClockSkewWarning is the skew above which timestamps start to disagree
+	ClockSkewWarning = 30 * time.Second
+	// ClockSkewFailure is the skew above which TLS certificates and SSH
+	// certificates may be rejected
+	ClockSkewFailure = 5 * time.Minute
+)
+
+// limitWarningRatio is the share of a resource limit above which a warning is shown
+const limitWarningRatio = 0.8
+
+// Diagnostic is the result of a single environment check
+type Diagnostic struct {
+	Name    string           `json:"name" yaml:"name"`
+	Status  DiagnosticStatus `json:"status" yaml:"status"`
+	Message string           `json:"message" yaml:"message"`
+	Hint    string           `json:"hint,omitempty" yaml:"hint,omitempty"`
+}
+
+// Diagnose runs the API checks for a server configuration: connection,
+// account permissions, zone, plan, resource limits, template and clock skew.
+// Checks needing the API are skipped when the connection fails.
+func (c *Client) Diagnose(ctx context.Context, serverConfig *ServerConfig) []Diagnostic {
+	diagnostics := []Diagnostic{c.diagnoseConnection(ctx)}
+	connected := diagnostics[0].Status == DiagnosticPass
+
+	apiCheck := func(name string, check func() Diagnostic) {
+		if !connected {
+			diagnostics = append(diagnostics, Diagnostic{
+				Name:    name,
+				Status:  DiagnosticSkip,
+				Message: "skipped, the UpCloud API is not reachable",
+			})
+			return
+		}
+		diagnostics = append(diagnostics, check())
+	}
+
+	apiCheck("Permissions", func() Diagnostic { return c.diagnosePermissions(ctx) })
+	apiCheck("Zone", func() Diagnostic { return c.diagnoseZone(ctx, serverConfig.Zone) })
+	apiCheck("Plan", func() Diagnostic { return c.diagnosePlan(ctx, serverConfig) })
+	apiCheck("Resource limits", func() Diagnostic { return c.diagnoseLimits(ctx, serverConfig) })
+	apiCheck("Template", func() Diagnostic { return c.diagnoseTemplate(ctx, serverConfig) })
+	diagnostics = append(diagnostics, diagnoseClockSkew(ctx))
+
+	return diagnostics
+}
+
+// diagnoseConnection checks the credentials against the API
+func (c *Client) diagnoseConnection(ctx context.Context) Diagnostic {
+	diagnostic := Diagnostic{Name: "Connection"}
+
+	if err := c.TestConnection(ctx); err != nil {
+		diagnostic.Status = DiagnosticFail
+		diagnostic.Message = err.Error()
+		if IsAuthenticationError(err) {
+			diagnostic.Hint = "check UPCLOUD_USERNAME and UPCLOUD_PASSWORD, and that API access is enabled for the account in the UpCloud control panel"
+		} else {
+			diagnostic.Hint = "check the network connection to api.upcloud.com"
+		}
+		return diagnostic
+	}
+
+	diagnostic.Status = DiagnosticPass
+	diagnostic.Message = "authenticated with the UpCloud API"
+	return diagnostic
+}
+
+// diagnosePermissions checks what a sub-account is allowed to manage
+func (c *Client) diagnosePermissions(ctx context.Context) Diagnostic {
+	diagnostic := Diagnostic{Name: "Permissions", Status: DiagnosticPass}
+
+	// Test mode
+	if c.service == nil {
+		diagnostic.Message = "test mode"
+		return diagnostic
+	}
+
+	account, err := c.service.GetAccount(ctx)
+	if err != nil {
+		return diagnosticFromError(diagnostic.Name, WrapError(err, "account"))
+	}
+	details, err := c.service.GetAccountDetails(ctx, &request.GetAccountDetailsRequest{Username: account.UserName})
+	if err != nil {
+		diagnostic.Status = DiagnosticWarn
+		diagnostic.Message = fmt.Sprintf("could not read the account permissions: %v", WrapError(err, "account details"))
+		diagnostic.Hint = "the main account can check the sub-account's permissions under People in the control panel"
+		return diagnostic
+	}
+
+	if !details.AllowAPI.Bool() {
+		diagnostic.Status = DiagnosticFail
+		diagnostic.Message = fmt.Sprintf("API access is disabled for %s", details.Username)
+		diagnostic.Hint = "enable API connections for the account in the UpCloud control panel"
+		return diagnostic
+	}
+
+	var problems []string
+	if details.IsSubaccount() && !slices.Contains(details.StorageAccess.Storage, "*") {
+		problems = append(problems, fmt.Sprintf("the sub-account can only access %d storage(s), private templates and disks created by others are not usable", len(details.StorageAccess.Storage)))
+	}
+	if len(details.IPFilters.IPFilter) > 0 {
+		problems = append(problems, fmt.Sprintf("API access is limited to %s", strings.Join(details.IPFilters.IPFilter, ", ")))
+	}
+
+	if len(problems) > 0 {
+		diagnostic.Status = DiagnosticWarn
+		diagnostic.Message = strings.Join(problems, "; ")
+		diagnostic.Hint = "grant the sub-account access to all storages, and allow this host's address, under People in the control panel"
+		return diagnostic
+	}
+
+	if details.IsSubaccount() {
+		diagnostic.Message = fmt.Sprintf("sub-account %s of %s has API access to all storages", details.Username, details.MainAccount)
+	} else {
+		diagnostic.Message = fmt.Sprintf("main account %s has full API access", details.Username)
+	}
+	return diagnostic
+}
+
+// diagnoseZone checks the zone is known and sells servers
+func (c *Client) diagnoseZone(ctx context.Context, zone string) Diagnostic {
+	diagnostic := Diagnostic{Name: "Zone"}
+
+	if err := ValidateZone(zone); err != nil {
+		return diagnosticFromError(diagnostic.Name, err)
+	}
+
+	prices, err := c.GetZonePrices(ctx)
+	if err != nil {
+		diagnostic.Status = DiagnosticWarn
+		diagnostic.Message = fmt.Sprintf("%s is a known zone, but its availability could not be checked: %v", zone, err)
+		return diagnostic
+	}
+	if _, ok := prices[zone]; !ok && c.service != nil {
+		diagnostic.Status = DiagnosticFail
+		diagnostic.Message = fmt.Sprintf("zone %s is not available to this account", zone)
+		diagnostic.Hint = "pick another zone with UPCLOUD_ZONE"
+		return diagnostic
+	}
+
+	diagnostic.Status = DiagnosticPass
+	diagnostic.Message = fmt.Sprintf("zone %s is available", zone)
+	return diagnostic
+}
+
+// diagnosePlan checks the plan exists, is sold in the zone and gets a storage tier it accepts
+func (c *Client) diagnosePlan(ctx context.Context, serverConfig *ServerConfig) Diagnostic {
+	plan, err := MapPlanName(serverConfig.Plan)
+	if err != nil {
+		diagnostic := diagnosticFromError("Plan", err)
+		diagnostic.Hint = "run 'devpod-provider-upcloud plans' to list the supported plans"
+		return diagnostic
+	}
+
+	// Test mode
+	if c.service == nil {
+		return Diagnostic{Name: "Plan", Status: DiagnosticPass, Message: "test mode"}
+	}
+
+	result := &PreflightResult{}
+	c.checkPlanInZone(ctx, result, plan, serverConfig.Zone)
+	c.checkPlanTier(ctx, result, plan)
+
+	diagnostic := diagnosticFromPreflight("Plan", result, fmt.Sprintf("plan %s is available in %s", plan, serverConfig.Zone))
+	if diagnostic.Status == DiagnosticFail {
+		diagnostic.Hint = "run 'devpod-provider-upcloud plans' and pick a plan sold in the zone with UPCLOUD_PLAN"
+	}
+	return diagnostic
+}
+
+// diagnoseTemplate checks the image or custom template is usable in the zone
+func (c *Client) diagnoseTemplate(ctx context.Context, serverConfig *ServerConfig) Diagnostic {
+	templateUUID := serverConfig.Template
+	if templateUUID == "" {
+		var err error
+		templateUUID, err = c.ResolveImage(ctx, serverConfig.Image)
+		if err != nil {
+			diagnostic := diagnosticFromError("Template", err)
+			diagnostic.Hint = "run 'devpod-provider-upcloud images' to list the available images"
+			return diagnostic
+		}
+	}
+
+	// Test mode
+	if c.service == nil {
+		return Diagnostic{Name: "Template", Status: DiagnosticPass, Message: "test mode"}
+	}
+
+	storageSize, _ := ParseStorageSize(serverConfig.Storage)
+	result := &PreflightResult{}
+	c.checkTemplate(ctx, result, templateUUID, serverConfig.Zone, storageSize)
+
+	return diagnosticFromPreflight("Template", result, fmt.Sprintf("template %s is accessible in %s", templateUUID, serverConfig.Zone))
+}
+
+// diagnoseLimits compares the account's resource limits with its usage plus
+// what a new workspace needs, including the per-account limits of restricted plans
+func (c *Client) diagnoseLimits(ctx context.Context, serverConfig *ServerConfig) Diagnostic {
+	diagnostic := Diagnostic{Name: "Resource limits"}
+
+	plan, err := MapPlanName(serverConfig.Plan)
+	if err != nil {
+		diagnostic.Status = DiagnosticSkip
+		diagnostic.Message = "skipped, the plan is invalid"
+		return diagnostic
+	}
+
+	// Test mode
+	if c.service == nil {
+		diagnostic.Status = DiagnosticPass
+		diagnostic.Message = "test mode"
+		return diagnostic
+	}
+
+	account, err := c.service.GetAccount(ctx)
+	if err != nil {
+		return diagnosticFromError(diagnostic.Name, WrapError(err, "account"))
+	}
+	usage, err := c.resourceUsage(ctx)
+	if err != nil {
+		return diagnosticFromError(diagnostic.Name, err)
+	}
+
+	needed := resourceUsage{IPv4: 1, Storage: map[string]int{}}
+	plans, err := c.service.GetPlans(ctx)
+	if err != nil {
+		return diagnosticFromError(diagnostic.Name, WrapError(err, "listing plans"))
+	}
+	for _, livePlan := range plans.Plans {
+		if livePlan.Name == plan {
+			needed.Cores, needed.Memory = livePlan.CoreNumber, livePlan.MemoryAmount
+		}
+	}
+	if size, err := ParseStorageSize(serverConfig.Storage); err == nil {
+		needed.Storage[GetStorageTier(plan)] = size
+	}
+
+	limits := account.ResourceLimits
+	checks := []struct {
+		name         string
+		limit        int
+		used, needed int
+	}{
+		{"cores", limits.Cores, usage.Cores, needed.Cores},
+		{"memory (MB)", limits.Memory, usage.Memory, needed.Memory},
+		{"public IPv4 addresses", limits.PublicIPv4, usage.IPv4, needed.IPv4},
+		{"standard storage (GB)", limits.StorageSSD, usage.Storage[upcloud.StorageTierStandard], needed.Storage[upcloud.StorageTierStandard]},
+		{"MaxIOPS storage (GB)", limits.StorageMaxIOPS, usage.Storage[upcloud.StorageTierMaxIOPS], needed.Storage[upcloud.StorageTierMaxIOPS]},
+		{"HDD storage (GB)", limits.StorageHDD, usage.Storage[upcloud.StorageTierHDD], needed.Storage[upcloud.StorageTierHDD]},
+	}
+
+	result := &PreflightResult{}
+	for _, check := range checks {
+		// Unset limits are not enforced
+		if check.limit == 0 {
+			continue
+		}
+		switch total := check.used + check.needed; {
+		case total > check.limit:
+			result.addError("%s: %d of %d used, a new workspace needs %d more", check.name, check.used, check.limit, check.needed)
+		case float64(total) > limitWarningRatio*float64(check.limit):
+			result.addWarning("%s: %d of %d used, a new workspace needs %d more", check.name, check.used, check.limit, check.needed)
+		}
+	}
+
+	if catalog, err := config.LoadServerPlans(); err == nil {
+		c.checkPlanLimit(ctx, result, catalog, plan)
+	}
+
+	diagnostic = diagnosticFromPreflight(diagnostic.Name, result, fmt.Sprintf("%d cores, %d MB memory and %d public IPv4 addresses in use, within the account limits", usage.Cores, usage.Memory, usage.IPv4))
+	if diagnostic.Status != DiagnosticPass {
+		diagnostic.Hint = "delete unused servers and storages ('devpod-provider-upcloud prune' finds orphans) or ask UpCloud support to raise the limits"
+	}
+	return diagnostic
+}
+
+// resourceUsage is the amount of limited resources an account uses
+type resourceUsage struct {
+	Cores  int
+	Memory int
+	IPv4   int
+	// Storage is the size in GB per storage tier
+	Storage map[string]int
+}
+
+// resourceUsage sums the cores, memory, public IPv4 addresses and storage of the account
+func (c *Client) resourceUsage(ctx context.Context) (*resourceUsage, error) {
+	usage := &resourceUsage{Storage: map[string]int{}}
+
+	servers, err := c.service.GetServers(ctx)
+	if err != nil {
+		return nil, WrapError(err, "listing servers")
+	}
+	for _, server := range servers.Servers {
+		usage.Cores += server.CoreNumber
+		usage.Memory += server.MemoryAmount
+	}
+
+	addresses, err := c.service.GetIPAddresses(ctx)
+	if err != nil {
+		return nil, WrapError(err, "listing IP addresses")
+	}
+	for _, address := range addresses.IPAddresses {
+		if address.Access == upcloud.IPAddressAccessPublic && address.Family == upcloud.IPAddressFamilyIPv4 {
+			usage.IPv4++
+		}
+	}
+
+	storages, err := c.service.GetStorages(ctx, &request.GetStoragesRequest{
+		Access: upcloud.StorageAccessPrivate,
+		Type:   upcloud.StorageTypeNormal,
+	})
+	if err != nil {
+		return nil, WrapError(err, "listing storages")
+	}
+	for _, storage := range storages.Storages {
+		usage.Storage[storage.Tier] += storage.Size
+	}
+
+	return usage, nil
+}
+
+// diagnoseClockSkew compares the local clock with the API server's Date header
+func diagnoseClockSkew(ctx context.Context) Diagnostic {
+	return clockSkewDiagnostic(ClockSkew(ctx))
+}
+
+// clockSkewDiagnostic rates a measured clock skew against the thresholds
+func clockSkewDiagnostic(skew time.Duration, err error) Diagnostic {
+	diagnostic := Diagnostic{Name: "Clock"}
+
+	if err != nil {
+		diagnostic.Status = DiagnosticWarn
+		diagnostic.Message = fmt.Sprintf("could not check the clock: %v", err)
+		return diagnostic
+	}
+
+	abs := skew.Abs().Round(time.Second)
+	switch {
+	case abs > ClockSkewFailure:
+		diagnostic.Status = DiagnosticFail
+	case abs > ClockSkewWarning:
+		diagnostic.Status = DiagnosticWarn
+	default:
+		diagnostic.Status = DiagnosticPass
+		diagnostic.Message = fmt.Sprintf("local clock is within %s of UpCloud", ClockSkewWarning)
+		return diagnostic
+	}
+
+	direction := "ahead of"
+	if skew < 0 {
+		direction = "behind"
+	}
+	diagnostic.Message = fmt.Sprintf("local clock is %s %s UpCloud", abs, direction)
+	diagnostic.Hint = "enable time synchronisation (NTP) on this host"
+	return diagnostic
+}
+
+// ClockSkew returns how far the local clock is ahead of the UpCloud API
+// server's clock, measured against the Date header with a one second resolution
+func ClockSkew(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, client.APIBaseURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	received := time.Now()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid Date header: %w", err)
+	}
+
+	// Compare with the middle of the round trip
+	local := sent.Add(received.Sub(sent) / 2)
+	return local.Sub(serverTime), nil
+}
+
+// diagnosticFromError turns an error into a failed diagnostic, keeping its hint
+func diagnosticFromError(name string, err error) Diagnostic {
+	diagnostic := Diagnostic{Name: name, Status: DiagnosticFail, Message: err.Error()}
+	if perr, ok := err.(*ProviderError); ok && perr.Hint != "" {
+		diagnostic.Message = perr.Message
+		if perr.Err != nil {
+			diagnostic.Message = fmt.Sprintf("%s: %v", perr.Message, perr.Err)
+		}
+		diagnostic.Hint = perr.Hint
+	}
+	return diagnostic
+}
+
+// diagnosticFromPreflight turns preflight errors and warnings into a diagnostic
+func diagnosticFromPreflight(name string, result *PreflightResult, passMessage string) Diagnostic {
+	switch {
+	case len(result.Errors) > 0:
+		return Diagnostic{Name: name, Status: DiagnosticFail, Message: strings.Join(append(result.Errors, result.Warnings...), "; ")}
+	case len(result.Warnings) > 0:
+		return Diagnostic{Name: name, Status: DiagnosticWarn, Message: strings.Join(result.Warnings, "; ")}
+	default:
+		return Diagnostic{Name: name, Status: DiagnosticPass, Message: passMessage}
+	}
+}
@@ -0,0 +1,257 @@
+package upcloud
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+)
+
+// newLimitsFake returns an account using 2 cores, 4 GB of memory, one public
+// IPv4 address, 40 GB of standard and 500 GB of MaxIOPS storage
+func newLimitsFake() *fakeService {
+	fake := newFakeService()
+	fake.account = upcloud.Account{UserName: "devpod"}
+	server := fake.addServer("server-uuid", "devpod-existing", upcloud.ServerStateStarted, "standard-disk", "maxiops-disk")
+	server.CoreNumber, server.MemoryAmount = 2, 4096
+	fake.storages["standard-disk"].Size, fake.storages["standard-disk"].Tier = 40, upcloud.StorageTierStandard
+	fake.storages["maxiops-disk"].Size, fake.storages["maxiops-disk"].Tier = 500, upcloud.StorageTierMaxIOPS
+	fake.storages["template"] = &upcloud.StorageDetails{
+		Storage: upcloud.Storage{UUID: "template", Size: 10, Tier: upcloud.StorageTierStandard, Type: upcloud.StorageTypeTemplate, Access: upcloud.StorageAccessPrivate},
+	}
+	fake.addresses = []upcloud.IPAddress{
+		{Access: upcloud.IPAddressAccessPublic, Family: upcloud.IPAddressFamilyIPv4},
+		{Access: upcloud.IPAddressAccessPublic, Family: upcloud.IPAddressFamilyIPv6},
+		{Access: upcloud.IPAddressAccessUtility, Family: upcloud.IPAddressFamilyIPv4},
+	}
+	fake.plans = []upcloud.Plan{
+		{Name: "DEV-2xCPU-4GB", CoreNumber: 2, MemoryAmount: 4096},
+		{Name: "2xCPU-4GB", CoreNumber: 2, MemoryAmount: 4096},
+	}
+	return fake
+}
+
+func TestResourceUsage(t *testing.T) {
+	client := newLimitsFake().client()
+
+	usage, err := client.resourceUsage(context.Background())
+	if err != nil {
+		t.Fatalf("resourceUsage() error = %v", err)
+	}
+
+	// Templates do not count towards the storage limits
+	want := &resourceUsage{
+		Cores:  2,
+		Memory: 4096,
+		IPv4:   1,
+		Storage: map[string]int{
+			upcloud.StorageTierStandard: 40,
+			upcloud.StorageTierMaxIOPS:  500,
+		},
+	}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("resourceUsage() = %+v, want %+v", usage, want)
+	}
+}
+
+func TestDiagnoseLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		plan        string
+		limits      upcloud.ResourceLimits
+		wantStatus  DiagnosticStatus
+		wantMessage string
+	}{
+		{
+			name:       "Unset limits are not enforced",
+			plan:       "DEV-2xCPU-4GB",
+			wantStatus: DiagnosticPass,
+		},
+		{
+			name:       "Within the limits",
+			plan:       "DEV-2xCPU-4GB",
+			limits:     upcloud.ResourceLimits{Cores: 20, Memory: 40960, PublicIPv4: 5, StorageSSD: 1000, StorageMaxIOPS: 1000},
+			wantStatus: DiagnosticPass,
+		},
+		{
+			name:       "Exactly at the warning ratio",
+			plan:       "DEV-2xCPU-4GB",
+			limits:     upcloud.ResourceLimits{Cores: 5},
+			wantStatus: DiagnosticPass,
+		},
+		{
+			name:        "Above the warning ratio",
+			plan:        "DEV-2xCPU-4GB",
+			limits:      upcloud.ResourceLimits{Cores: 4},
+			wantStatus:  DiagnosticWarn,
+			wantMessage: "cores: 2 of 4 used, a new workspace needs 2 more",
+		},
+		{
+			name:        "Over the limit",
+			plan:        "DEV-2xCPU-4GB",
+			limits:      upcloud.ResourceLimits{Memory: 6144},
+			wantStatus:  DiagnosticFail,
+			wantMessage: "memory (MB): 4096 of 6144 used, a new workspace needs 4096 more",
+		},
+		{
+			name:        "Only public IPv4 addresses are counted",
+			plan:        "DEV-2xCPU-4GB",
+			limits:      upcloud.ResourceLimits{PublicIPv4: 1},
+			wantStatus:  DiagnosticFail,
+			wantMessage: "public IPv4 addresses: 1 of 1 used, a new workspace needs 1 more",
+		},
+		{
+			name:        "Developer plans need standard storage",
+			plan:        "DEV-2xCPU-4GB",
+			limits:      upcloud.ResourceLimits{StorageSSD: 85, StorageMaxIOPS: 600},
+			wantStatus:  DiagnosticFail,
+			wantMessage: "standard storage (GB): 40 of 85 used, a new workspace needs 50 more",
+		},
+		{
+			name:        "General purpose plans need MaxIOPS storage",
+			plan:        "2xCPU-4GB",
+			limits:      upcloud.ResourceLimits{StorageSSD: 85, StorageMaxIOPS: 600},
+			wantStatus:  DiagnosticWarn,
+			wantMessage: "MaxIOPS storage (GB): 500 of 600 used, a new workspace needs 50 more",
+		},
+		{
+			name:       "Invalid plan",
+			plan:       "NOT-A-PLAN",
+			limits:     upcloud.ResourceLimits{Cores: 1},
+			wantStatus: DiagnosticSkip,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newLimitsFake()
+			fake.account.ResourceLimits = tt.limits
+
+			diagnostic := fake.client().diagnoseLimits(context.Background(), &ServerConfig{Plan: tt.plan, Storage: "50"})
+			if diagnostic.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s (%s)", diagnostic.Status, tt.wantStatus, diagnostic.Message)
+			}
+			if tt.wantMessage != "" && !strings.Contains(diagnostic.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", diagnostic.Message, tt.wantMessage)
+			}
+			if (tt.wantStatus == DiagnosticWarn || tt.wantStatus == DiagnosticFail) && diagnostic.Hint == "" {
+				t.Error("Hint is empty")
+			}
+		})
+	}
+}
+
+func TestDiagnoseLimitsAPIError(t *testing.T) {
+	fake := newLimitsFake()
+	fake.failNext("GetIPAddresses", errors.New("connection reset"))
+
+	diagnostic := fake.client().diagnoseLimits(context.Background(), &ServerConfig{Plan: "DEV-2xCPU-4GB", Storage: "50"})
+	if diagnostic.Status != DiagnosticFail || !strings.Contains(diagnostic.Message, "connection reset") {
+		t.Errorf("diagnoseLimits() = %+v, want a failure with the API error", diagnostic)
+	}
+}
+
+func TestDiagnosePermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		details     upcloud.AccountDetails
+		detailsErr  error
+		wantStatus  DiagnosticStatus
+		wantMessage string
+	}{
+		{
+			name:        "Main account",
+			details:     upcloud.AccountDetails{Username: "devpod", Type: upcloud.AccountTypeMain, AllowAPI: upcloud.True},
+			wantStatus:  DiagnosticPass,
+			wantMessage: "main account devpod has full API access",
+		},
+		{
+			name:        "API access disabled",
+			details:     upcloud.AccountDetails{Username: "devpod", Type: upcloud.AccountTypeSubaccount, AllowAPI: upcloud.False},
+			wantStatus:  DiagnosticFail,
+			wantMessage: "API access is disabled for devpod",
+		},
+		{
+			name: "Sub-account with access to all storages",
+			details: upcloud.AccountDetails{
+				Username: "devpod", MainAccount: "owner", Type: upcloud.AccountTypeSubaccount, AllowAPI: upcloud.True,
+				StorageAccess: upcloud.AccountStorageAccess{Storage: []string{"*"}},
+			},
+			wantStatus:  DiagnosticPass,
+			wantMessage: "sub-account devpod of owner",
+		},
+		{
+			name: "Sub-account limited to some storages",
+			details: upcloud.AccountDetails{
+				Username: "devpod", Type: upcloud.AccountTypeSubaccount, AllowAPI: upcloud.True,
+				StorageAccess: upcloud.AccountStorageAccess{Storage: []string{"storage-1", "storage-2"}},
+			},
+			wantStatus:  DiagnosticWarn,
+			wantMessage: "can only access 2 storage(s)",
+		},
+		{
+			name: "IP filter",
+			details: upcloud.AccountDetails{
+				Username: "devpod", Type: upcloud.AccountTypeMain, AllowAPI: upcloud.True,
+				IPFilters: upcloud.AccountIPFilters{IPFilter: []string{"192.0.2.0/24"}},
+			},
+			wantStatus:  DiagnosticWarn,
+			wantMessage: "API access is limited to 192.0.2.0/24",
+		},
+		{
+			name:        "Account details not readable",
+			details:     upcloud.AccountDetails{Username: "devpod"},
+			detailsErr:  errors.New("forbidden"),
+			wantStatus:  DiagnosticWarn,
+			wantMessage: "could not read the account permissions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeService()
+			fake.account = upcloud.Account{UserName: "devpod"}
+			fake.details = tt.details
+			if tt.detailsErr != nil {
+				fake.failNext("GetAccountDetails", tt.detailsErr)
+			}
+
+			diagnostic := fake.client().diagnosePermissions(context.Background())
+			if diagnostic.Status != tt.wantStatus || !strings.Contains(diagnostic.Message, tt.wantMessage) {
+				t.Errorf("diagnosePermissions() = %s %q, want %s %q", diagnostic.Status, diagnostic.Message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestClockSkewDiagnostic(t *testing.T) {
+	tests := []struct {
+		name        string
+		skew        time.Duration
+		err         error
+		wantStatus  DiagnosticStatus
+		wantMessage string
+	}{
+		{"In sync", 0, nil, DiagnosticPass, "within 30s"},
+		{"At the warning threshold", ClockSkewWarning, nil, DiagnosticPass, "within 30s"},
+		{"Rounded to the warning threshold", ClockSkewWarning + 400*time.Millisecond, nil, DiagnosticPass, "within 30s"},
+		{"Ahead beyond the warning threshold", 31 * time.Second, nil, DiagnosticWarn, "31s ahead of"},
+		{"Behind beyond the warning threshold", -2 * time.Minute, nil, DiagnosticWarn, "2m0s behind"},
+		{"At the failure threshold", ClockSkewFailure, nil, DiagnosticWarn, "5m0s ahead of"},
+		{"Beyond the failure threshold", -10 * time.Minute, nil, DiagnosticFail, "10m0s behind"},
+		{"Not measurable", 0, errors.New("timeout"), DiagnosticWarn, "could not check the clock: timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostic := clockSkewDiagnostic(tt.skew, tt.err)
+			if diagnostic.Status != tt.wantStatus || !strings.Contains(diagnostic.Message, tt.wantMessage) {
+				t.Errorf("clockSkewDiagnostic(%s) = %s %q, want %s %q", tt.skew, diagnostic.Status, diagnostic.Message, tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
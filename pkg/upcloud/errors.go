@@ -26,13 +26,19 @@ type ProviderError struct {
 	Type    ErrorType
 	Message string
 	Err     error
+	// Hint optionally tells the user how to fix the problem
+	Hint string
 }
 
 func (e *ProviderError) Error() string {
+	message := e.Message
 	if e.Err != nil {
-		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+		message = fmt.Sprintf("%s: %v", e.Message, e.Err)
 	}
-	return e.Message
+	if e.Hint != "" {
+		message = fmt.Sprintf("%s (hint: %s)", message, e.Hint)
+	}
+	return message
 }
 
 func (e *ProviderError) Unwrap() error {
@@ -186,3 +192,14 @@ func IsQuotaError(err error) bool {
 	}
 	return false
 }
+
+// IsServerBusyError checks if the error is a conflict or busy error
+func IsServerBusyError(err error) bool {
+	if perr, ok := err.(*ProviderError); ok {
+		return perr.Type == ErrorTypeServerBusy
+	}
+	if problem, ok := err.(*upcloud.Problem); ok {
+		return problem.Status == 409 || problem.Status == 503
+	}
+	return false
+}
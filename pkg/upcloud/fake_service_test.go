@@ -0,0 +1,352 @@
+package upcloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+)
+
+// fakeService is an in-memory UpCloud API for tests. Servers move through
+// maintenance on start and stop and only reach the requested state while
+// being waited for, and labels cannot be modified during maintenance, as
+// with the real API. Methods that are not faked panic.
+type fakeService struct {
+	upcloudService
+
+	servers   map[string]*upcloud.ServerDetails
+	storages  map[string]*upcloud.StorageDetails
+	account   upcloud.Account
+	details   upcloud.AccountDetails
+	addresses []upcloud.IPAddress
+	plans     []upcloud.Plan
+
+	// errors are returned in order by the next calls of the named methods
+	errors map[string][]error
+	// calls records the called methods in order
+	calls []string
+}
+
+func newFakeService() *fakeService {
+	return &fakeService{
+		servers:  map[string]*upcloud.ServerDetails{},
+		storages: map[string]*upcloud.StorageDetails{},
+		errors:   map[string][]error{},
+	}
+}
+
+// client returns a client using the fake service
+func (f *fakeService) client() *Client {
+	return &Client{service: f, timeout: time.Minute}
+}
+
+// addServer adds a server with a disk per storage UUID, the first one booting
+func (f *fakeService) addServer(uuid, title, state string, storageUUIDs ...string) *upcloud.ServerDetails {
+	server := &upcloud.ServerDetails{
+		Server: upcloud.Server{UUID: uuid, Title: title, Hostname: title, State: state, Zone: "de-fra1", Plan: "DEV-2xCPU-4GB"},
+	}
+	for i, storageUUID := range storageUUIDs {
+		device := upcloud.ServerStorageDevice{UUID: storageUUID, Type: upcloud.StorageTypeDisk, Size: 50}
+		if i == 0 {
+			device.BootDisk = 1
+		}
+		server.StorageDevices = append(server.StorageDevices, device)
+		f.storages[storageUUID] = &upcloud.StorageDetails{
+			Storage: upcloud.Storage{UUID: storageUUID, Size: 50, State: upcloud.StorageStateOnline, Type: upcloud.StorageTypeNormal, Access: upcloud.StorageAccessPrivate},
+		}
+	}
+	f.servers[uuid] = server
+	return server
+}
+
+// failNext makes the next calls of a method return the errors
+func (f *fakeService) failNext(method string, errs ...error) {
+	f.errors[method] = append(f.errors[method], errs...)
+}
+
+// call records a call and returns the next error configured for the method
+func (f *fakeService) call(method string) error {
+	f.calls = append(f.calls, method)
+	if len(f.errors[method]) == 0 {
+		return nil
+	}
+	err := f.errors[method][0]
+	f.errors[method] = f.errors[method][1:]
+	return err
+}
+
+func (f *fakeService) server(uuid string) (*upcloud.ServerDetails, error) {
+	server, ok := f.servers[uuid]
+	if !ok {
+		return nil, &upcloud.Problem{Type: "SERVER_NOT_FOUND", Title: "Server not found", Status: http.StatusNotFound}
+	}
+	return server, nil
+}
+
+func (f *fakeService) storage(uuid string) (*upcloud.StorageDetails, error) {
+	storage, ok := f.storages[uuid]
+	if !ok {
+		return nil, &upcloud.Problem{Type: "STORAGE_NOT_FOUND", Title: "Storage not found", Status: http.StatusNotFound}
+	}
+	return storage, nil
+}
+
+func (f *fakeService) GetServers(_ context.Context) (*upcloud.Servers, error) {
+	if err := f.call("GetServers"); err != nil {
+		return nil, err
+	}
+	servers := &upcloud.Servers{}
+	for _, server := range f.servers {
+		servers.Servers = append(servers.Servers, server.Server)
+	}
+	sort.Slice(servers.Servers, func(i, j int) bool { return servers.Servers[i].UUID < servers.Servers[j].UUID })
+	return servers, nil
+}
+
+func (f *fakeService) GetServerDetails(_ context.Context, r *request.GetServerDetailsRequest) (*upcloud.ServerDetails, error) {
+	if err := f.call("GetServerDetails"); err != nil {
+		return nil, err
+	}
+	server, err := f.server(r.UUID)
+	if err != nil {
+		return nil, err
+	}
+	details := *server
+	details.Labels = append(upcloud.LabelSlice{}, server.Labels...)
+	return &details, nil
+}
+
+func (f *fakeService) CreateServer(_ context.Context, r *request.CreateServerRequest) (*upcloud.ServerDetails, error) {
+	if err := f.call("CreateServer"); err != nil {
+		return nil, err
+	}
+	uuid := fmt.Sprintf("server-%d", len(f.servers)+1)
+	server := f.addServer(uuid, r.Title, upcloud.ServerStateMaintenance, uuid+"-disk")
+	server.Zone = r.Zone
+	server.Plan = r.Plan
+	if r.Labels != nil {
+		server.Labels = *r.Labels
+	}
+	return server, nil
+}
+
+func (f *fakeService) StartServer(_ context.Context, r *request.StartServerRequest) (*upcloud.ServerDetails, error) {
+	if err := f.call("StartServer"); err != nil {
+		return nil, err
+	}
+	server, err := f.server(r.UUID)
+	if err != nil {
+		return nil, err
+	}
+	server.State = upcloud.ServerStateMaintenance
+	return server, nil
+}
+
+func (f *fakeService) StopServer(_ context.Context, r *request.StopServerRequest) (*upcloud.ServerDetails, error) {
+	if err := f.call("StopServer"); err != nil {
+		return nil, err
+	}
+	server, err := f.server(r.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if server.State != upcloud.ServerStateStarted {
+		return nil, &upcloud.Problem{Type: "SERVER_STATE_ILLEGAL", Title: "The server is not started", Status: http.StatusConflict}
+	}
+	server.State = upcloud.ServerStateMaintenance
+	return server, nil
+}
+
+func (f *fakeService) WaitForServerState(_ context.Context, r *request.WaitForServerStateRequest) (*upcloud.ServerDetails, error) {
+	if err := f.call("WaitForServerState"); err != nil {
+		return nil, err
+	}
+	server, err := f.server(r.UUID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case r.DesiredState != "":
+		server.State = r.DesiredState
+	case server.State == r.UndesiredState:
+		// Only waiting for maintenance to end is faked, after which the
+		// server has started
+		server.State = upcloud.ServerStateStarted
+	}
+	return server, nil
+}
+
+func (f *fakeService) ModifyServer(_ context.Context, r *request.ModifyServerRequest) (*upcloud.ServerDetails, error) {
+	if err := f.call("ModifyServer"); err != nil {
+		return nil, err
+	}
+	server, err := f.server(r.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if server.State == upcloud.ServerStateMaintenance {
+		return nil, &upcloud.Problem{Type: "SERVER_STATE_ILLEGAL", Title: "The server is in maintenance", Status: http.StatusConflict}
+	}
+	if r.Labels != nil {
+		server.Labels = *r.Labels
+	}
+	return server, nil
+}
+
+func (f *fakeService) DeleteServer(_ context.Context, r *request.DeleteServerRequest) error {
+	if err := f.call("DeleteServer"); err != nil {
+		return err
+	}
+	if _, err := f.server(r.UUID); err != nil {
+		return err
+	}
+	delete(f.servers, r.UUID)
+	return nil
+}
+
+func (f *fakeService) DeleteServerAndStorages(_ context.Context, r *request.DeleteServerAndStoragesRequest) error {
+	if err := f.call("DeleteServerAndStorages"); err != nil {
+		return err
+	}
+	server, err := f.server(r.UUID)
+	if err != nil {
+		return err
+	}
+	if server.State != upcloud.ServerStateStopped {
+		return &upcloud.Problem{Type: "SERVER_STATE_ILLEGAL", Title: "The server is not stopped", Status: http.StatusConflict}
+	}
+	for _, device := range server.StorageDevices {
+		if storage, ok := f.storages[device.UUID]; ok && storage.State != upcloud.StorageStateOnline {
+			return &upcloud.Problem{Type: "STORAGE_STATE_ILLEGAL", Title: fmt.Sprintf("The storage is %s", storage.State), Status: http.StatusConflict}
+		}
+	}
+	for _, device := range server.StorageDevices {
+		delete(f.storages, device.UUID)
+	}
+	delete(f.servers, r.UUID)
+	return nil
+}
+
+func (f *fakeService) GetStorageDetails(_ context.Context, r *request.GetStorageDetailsRequest) (*upcloud.StorageDetails, error) {
+	if err := f.call("GetStorageDetails"); err != nil {
+		return nil, err
+	}
+	storage, err := f.storage(r.UUID)
+	if err != nil {
+		return nil, err
+	}
+	details := *storage
+	return &details, nil
+}
+
+func (f *fakeService) ModifyStorage(_ context.Context, r *request.ModifyStorageRequest) (*upcloud.StorageDetails, error) {
+	if err := f.call("ModifyStorage"); err != nil {
+		return nil, err
+	}
+	storage, err := f.storage(r.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if storage.State != upcloud.StorageStateOnline {
+		return nil, &upcloud.Problem{Type: "STORAGE_STATE_ILLEGAL", Title: fmt.Sprintf("The storage is %s", storage.State), Status: http.StatusConflict}
+	}
+	if r.Size > 0 {
+		storage.Size = r.Size
+	}
+	return storage, nil
+}
+
+func (f *fakeService) TemplatizeStorage(_ context.Context, r *request.TemplatizeStorageRequest) (*upcloud.StorageDetails, error) {
+	if err := f.call("TemplatizeStorage"); err != nil {
+		return nil, err
+	}
+	storage, err := f.storage(r.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if storage.State != upcloud.StorageStateOnline {
+		return nil, &upcloud.Problem{Type: "STORAGE_STATE_ILLEGAL", Title: fmt.Sprintf("The storage is %s", storage.State), Status: http.StatusConflict}
+	}
+
+	// Both storages are in maintenance until the copy has finished
+	storage.State = upcloud.StorageStateMaintenance
+	template := &upcloud.StorageDetails{
+		Storage: upcloud.Storage{UUID: r.UUID + "-template", Title: r.Title, Size: storage.Size, State: upcloud.StorageStateMaintenance, Type: upcloud.StorageTypeTemplate},
+	}
+	f.storages[template.UUID] = template
+	return template, nil
+}
+
+func (f *fakeService) WaitForStorageState(_ context.Context, r *request.WaitForStorageStateRequest) (*upcloud.StorageDetails, error) {
+	if err := f.call("WaitForStorageState"); err != nil {
+		return nil, err
+	}
+	storage, err := f.storage(r.UUID)
+	if err != nil {
+		return nil, err
+	}
+	storage.State = r.DesiredState
+	return storage, nil
+}
+
+func (f *fakeService) DeleteStorage(_ context.Context, r *request.DeleteStorageRequest) error {
+	if err := f.call("DeleteStorage"); err != nil {
+		return err
+	}
+	if _, err := f.storage(r.UUID); err != nil {
+		return err
+	}
+	delete(f.storages, r.UUID)
+	return nil
+}
+
+func (f *fakeService) GetAccount(_ context.Context) (*upcloud.Account, error) {
+	if err := f.call("GetAccount"); err != nil {
+		return nil, err
+	}
+	account := f.account
+	return &account, nil
+}
+
+func (f *fakeService) GetAccountDetails(_ context.Context, r *request.GetAccountDetailsRequest) (*upcloud.AccountDetails, error) {
+	if err := f.call("GetAccountDetails"); err != nil {
+		return nil, err
+	}
+	if r.Username != f.details.Username {
+		return nil, &upcloud.Problem{Type: "ACCOUNT_NOT_FOUND", Title: "Account not found", Status: http.StatusNotFound}
+	}
+	details := f.details
+	return &details, nil
+}
+
+func (f *fakeService) GetIPAddresses(_ context.Context) (*upcloud.IPAddresses, error) {
+	if err := f.call("GetIPAddresses"); err != nil {
+		return nil, err
+	}
+	return &upcloud.IPAddresses{IPAddresses: append([]upcloud.IPAddress{}, f.addresses...)}, nil
+}
+
+func (f *fakeService) GetPlans(_ context.Context) (*upcloud.Plans, error) {
+	if err := f.call("GetPlans"); err != nil {
+		return nil, err
+	}
+	return &upcloud.Plans{Plans: append([]upcloud.Plan{}, f.plans...)}, nil
+}
+
+func (f *fakeService) GetStorages(_ context.Context, r *request.GetStoragesRequest) (*upcloud.Storages, error) {
+	if err := f.call("GetStorages"); err != nil {
+		return nil, err
+	}
+	storages := &upcloud.Storages{}
+	for _, storage := range f.storages {
+		if (r.Type == "" || storage.Type == r.Type) && (r.Access == "" || storage.Access == r.Access) {
+			storages.Storages = append(storages.Storages, storage.Storage)
+		}
+	}
+	sort.Slice(storages.Storages, func(i, j int) bool { return storages.Storages[i].UUID < storages.Storages[j].UUID })
+	return storages, nil
+}
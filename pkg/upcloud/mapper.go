@@ -34,13 +34,14 @@ func MapPlanName(plan string) (string, error) {
 	return plan, nil
 }
 
-// MapImageToTemplate maps the OS image name to UpCloud template UUID
+// MapImageToTemplate maps the OS image name to UpCloud template UUID using
+// the embedded ImageMap. It is the offline fallback for Client.ResolveImage.
 func MapImageToTemplate(imageName string) (string, error) {
 	if templateUUID, ok := ImageMap[imageName]; ok {
 		return templateUUID, nil
 	}
 	// If not found in map, check if it's already a UUID
-	if strings.HasPrefix(imageName, "01000000-") {
+	if IsUUID(imageName) {
 		return imageName, nil
 	}
 	return "", fmt.Errorf("unknown image: %s", imageName)
@@ -155,3 +156,63 @@ func GetPublicIPv4(server *upcloud.ServerDetails) (string, error) {
 	}
 	return "", fmt.Errorf("no public IPv4 address found")
 }
+
+// FindRootStorage returns the boot disk of a server
+func FindRootStorage(server *upcloud.ServerDetails) (*upcloud.ServerStorageDevice, error) {
+	// Prefer the device flagged as boot disk
+	for i := range server.StorageDevices {
+		device := &server.StorageDevices[i]
+		if device.Type == upcloud.StorageTypeDisk && device.BootDisk == 1 {
+			return device, nil
+		}
+	}
+
+	// Fall back to the first disk, which is the root storage created by the provider
+	for i := range server.StorageDevices {
+		device := &server.StorageDevices[i]
+		if device.Type == upcloud.StorageTypeDisk {
+			return device, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no root storage found")
+}
+
+// IsStorageTierCompatible checks if a storage tier can be attached to servers of the given plan
+func IsStorageTierCompatible(plan, tier string) bool {
+	// DEV and CN plans only accept standard storage; other plans accept any tier
+	if GetStorageTier(plan) == upcloud.StorageTierStandard {
+		return tier == upcloud.StorageTierStandard
+	}
+	return true
+}
+
+// NewServerInfo converts UpCloud server details into a ServerInfo
+func NewServerInfo(server *upcloud.ServerDetails) *ServerInfo {
+	info := &ServerInfo{
+		MachineID: server.Title,
+		UUID:      server.UUID,
+		Hostname:  server.Hostname,
+		Status:    MapServerStateToStatus(server.State),
+		Plan:      server.Plan,
+		Zone:      server.Zone,
+	}
+
+	// A missing public address is not an error for display purposes
+	info.IP, _ = GetPublicIPv4(server)
+
+	for _, device := range server.StorageDevices {
+		if device.Type != upcloud.StorageTypeDisk {
+			continue
+		}
+		info.Storages = append(info.Storages, StorageInfo{
+			UUID:      device.UUID,
+			Title:     device.Title,
+			Size:      device.Size,
+			Tier:      device.Tier,
+			Encrypted: device.Encrypted.Bool(),
+		})
+	}
+
+	return info
+}
@@ -0,0 +1,87 @@
+package upcloud
+
+import (
+	"testing"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+)
+
+func TestIsStorageTierCompatible(t *testing.T) {
+	tests := []struct {
+		plan string
+		tier string
+		want bool
+	}{
+		{"DEV-2xCPU-4GB", upcloud.StorageTierStandard, true},
+		{"DEV-2xCPU-4GB", upcloud.StorageTierMaxIOPS, false},
+		{"CN-2xCPU-4GB", upcloud.StorageTierStandard, true},
+		{"CN-2xCPU-4GB", upcloud.StorageTierMaxIOPS, false},
+		{"2xCPU-4GB", upcloud.StorageTierMaxIOPS, true},
+		{"2xCPU-4GB", upcloud.StorageTierStandard, true},
+		{"HIMEM-4xCPU-32GB", upcloud.StorageTierHDD, true},
+	}
+
+	for _, tt := range tests {
+		if got := IsStorageTierCompatible(tt.plan, tt.tier); got != tt.want {
+			t.Errorf("IsStorageTierCompatible(%q, %q) = %v, want %v", tt.plan, tt.tier, got, tt.want)
+		}
+	}
+}
+
+func TestFindRootStorage(t *testing.T) {
+	tests := []struct {
+		name      string
+		devices   []upcloud.ServerStorageDevice
+		wantUUID  string
+		wantError bool
+	}{
+		{
+			name: "Boot disk first",
+			devices: []upcloud.ServerStorageDevice{
+				{UUID: "root", Type: upcloud.StorageTypeDisk, BootDisk: 1},
+				{UUID: "data", Type: upcloud.StorageTypeDisk},
+			},
+			wantUUID: "root",
+		},
+		{
+			name: "Boot disk after a data disk",
+			devices: []upcloud.ServerStorageDevice{
+				{UUID: "data", Type: upcloud.StorageTypeDisk},
+				{UUID: "root", Type: upcloud.StorageTypeDisk, BootDisk: 1},
+			},
+			wantUUID: "root",
+		},
+		{
+			name: "No boot flag falls back to the first disk",
+			devices: []upcloud.ServerStorageDevice{
+				{UUID: "cdrom", Type: upcloud.StorageTypeCDROM},
+				{UUID: "root", Type: upcloud.StorageTypeDisk},
+				{UUID: "data", Type: upcloud.StorageTypeDisk},
+			},
+			wantUUID: "root",
+		},
+		{
+			name:      "No disks",
+			devices:   []upcloud.ServerStorageDevice{{UUID: "cdrom", Type: upcloud.StorageTypeCDROM}},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindRootStorage(&upcloud.ServerDetails{StorageDevices: tt.devices})
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("Expected error, got %s", got.UUID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.UUID != tt.wantUUID {
+				t.Errorf("FindRootStorage() = %s, want %s", got.UUID, tt.wantUUID)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package upcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MetadataURL is the UpCloud metadata service, reachable only from servers
+const MetadataURL = "http://169.254.169.254/metadata/v1.json"
+
+// metadataTimeout keeps the check quick when not running on UpCloud
+const metadataTimeout = 2 * time.Second
+
+// InstanceMetadata is the part of the metadata service response the provider uses
+type InstanceMetadata struct {
+	CloudName  string `json:"cloud_name"`
+	InstanceID string `json:"instance_id"`
+	Hostname   string `json:"hostname"`
+	Region     string `json:"region"`
+}
+
+// GetInstanceMetadata queries the metadata service of the server the
+// provider runs on. It fails quickly when not running on an UpCloud server.
+func GetInstanceMetadata(ctx context.Context) (*InstanceMetadata, error) {
+	return getInstanceMetadata(ctx, MetadataURL)
+}
+
+// getInstanceMetadata queries the metadata service at url
+func getInstanceMetadata(ctx context.Context, url string) (*InstanceMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("metadata service not reachable: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata service returned %s", resp.Status)
+	}
+
+	metadata := &InstanceMetadata{}
+	if err := json.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		return nil, fmt.Errorf("decode metadata: %w", err)
+	}
+	if metadata.CloudName != "upcloud" {
+		return nil, fmt.Errorf("not running on UpCloud (cloud %q)", metadata.CloudName)
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,47 @@
+package upcloud
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetInstanceMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"UpCloud server", http.StatusOK, `{"cloud_name": "upcloud", "instance_id": "server-uuid", "hostname": "devpod-workspace", "region": "de-fra1"}`, ""},
+		{"Other cloud", http.StatusOK, `{"cloud_name": "aws"}`, `not running on UpCloud (cloud "aws")`},
+		{"Not a metadata service", http.StatusOK, `<html></html>`, "decode metadata"},
+		{"Error response", http.StatusNotFound, ``, "404 Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			metadata, err := getInstanceMetadata(context.Background(), server.URL)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("getInstanceMetadata() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getInstanceMetadata() error = %v", err)
+			}
+			if metadata.InstanceID != "server-uuid" || metadata.Region != "de-fra1" {
+				t.Errorf("getInstanceMetadata() = %+v", metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package upcloud
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+)
+
+// legacyStorageTitle is the title of root storages created before storages
+// were labelled
+const legacyStorageTitle = "root"
+
+// DetachedStorage is a workspace disk no longer attached to any server
+type DetachedStorage struct {
+	UUID      string    `json:"uuid" yaml:"uuid"`
+	Title     string    `json:"title" yaml:"title"`
+	Zone      string    `json:"zone" yaml:"zone"`
+	Tier      string    `json:"tier" yaml:"tier"`
+	Size      int       `json:"size_gb" yaml:"size_gb"`
+	MachineID string    `json:"machine_id,omitempty" yaml:"machine_id,omitempty"`
+	Owner     string    `json:"owner,omitempty" yaml:"owner,omitempty"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	// Labelled is false for root storages created before the provider
+	// labelled them, which are only recognised by their title
+	Labelled bool `json:"labelled" yaml:"labelled"`
+}
+
+// FloatingIP is a floating IP address and the server it is attached to, if any
+type FloatingIP struct {
+	Address    string `json:"address" yaml:"address"`
+	Zone       string `json:"zone" yaml:"zone"`
+	ServerUUID string `json:"server_uuid,omitempty" yaml:"server_uuid,omitempty"`
+}
+
+// ListDetachedStorages returns the private disks labelled by the provider
+// that are not attached to a server. With includeLegacy, unlabelled disks
+// titled "root", as created by older provider versions, are included too.
+func (c *Client) ListDetachedStorages(ctx context.Context, includeLegacy bool) ([]DetachedStorage, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating detached storage list\n")
+		return []DetachedStorage{}, nil
+	}
+
+	storages, err := c.service.GetStorages(ctx, &request.GetStoragesRequest{
+		Access: upcloud.StorageAccessPrivate,
+		Type:   upcloud.StorageTypeNormal,
+	})
+	if err != nil {
+		return nil, WrapError(err, "listing storages")
+	}
+
+	detached := []DetachedStorage{}
+	for _, storage := range storages.Storages {
+		entry := newDetachedStorage(&storage)
+		if !entry.Labelled && (!includeLegacy || storage.Title != legacyStorageTitle) {
+			continue
+		}
+
+		// Only the details tell which servers a storage is attached to
+		details, err := c.service.GetStorageDetails(ctx, &request.GetStorageDetailsRequest{UUID: storage.UUID})
+		if err != nil {
+			if IsNotFoundError(err) {
+				continue
+			}
+			return nil, WrapError(err, "storage details")
+		}
+		if len(details.ServerUUIDs) > 0 {
+			continue
+		}
+
+		detached = append(detached, *entry)
+	}
+
+	sort.Slice(detached, func(i, j int) bool {
+		return detached[i].CreatedAt.Before(detached[j].CreatedAt)
+	})
+
+	return detached, nil
+}
+
+// newDetachedStorage converts an UpCloud storage into a DetachedStorage
+func newDetachedStorage(storage *upcloud.Storage) *DetachedStorage {
+	entry := &DetachedStorage{
+		UUID:      storage.UUID,
+		Title:     storage.Title,
+		Zone:      storage.Zone,
+		Tier:      storage.Tier,
+		Size:      storage.Size,
+		CreatedAt: storage.Created,
+	}
+
+	for _, label := range storage.Labels {
+		switch label.Key {
+		case LabelManaged:
+			entry.Labelled = true
+		case LabelMachineID:
+			entry.MachineID = label.Value
+		case LabelOwner:
+			entry.Owner = label.Value
+		}
+	}
+
+	return entry
+}
+
+// ListFloatingIPs returns the account's floating IP addresses
+func (c *Client) ListFloatingIPs(ctx context.Context) ([]FloatingIP, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating floating IP list\n")
+		return []FloatingIP{}, nil
+	}
+
+	addresses, err := c.service.GetIPAddresses(ctx)
+	if err != nil {
+		return nil, WrapError(err, "listing IP addresses")
+	}
+
+	floating := []FloatingIP{}
+	for _, address := range addresses.IPAddresses {
+		if !address.Floating.Bool() {
+			continue
+		}
+		floating = append(floating, FloatingIP{
+			Address:    address.Address,
+			Zone:       address.Zone,
+			ServerUUID: address.ServerUUID,
+		})
+	}
+
+	return floating, nil
+}
+
+// DeleteServerByUUID deletes a server and its storages by UUID
+func (c *Client) DeleteServerByUUID(ctx context.Context, uuid string) error {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating server deletion for %s\n", uuid)
+		return nil
+	}
+
+	details, err := c.service.GetServerDetails(ctx, &request.GetServerDetailsRequest{UUID: uuid})
+	if err != nil {
+		if IsNotFoundError(err) {
+			// Already deleted
+			return nil
+		}
+		return WrapError(err, "server details")
+	}
+
+	return c.removeServer(ctx, uuid, details.State, true)
+}
+
+// DeleteStorage deletes a detached storage together with its backups
+func (c *Client) DeleteStorage(ctx context.Context, uuid string) error {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating storage deletion for %s\n", uuid)
+		return nil
+	}
+
+	err := c.service.DeleteStorage(ctx, &request.DeleteStorageRequest{
+		UUID:    uuid,
+		Backups: request.DeleteStorageBackupsModeDelete,
+	})
+	if err != nil && !IsNotFoundError(err) {
+		return WrapError(err, "storage deletion")
+	}
+	return nil
+}
+
+// ReleaseIP releases a floating IP address
+func (c *Client) ReleaseIP(ctx context.Context, address string) error {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating release of %s\n", address)
+		return nil
+	}
+
+	err := c.service.ReleaseIPAddress(ctx, &request.ReleaseIPAddressRequest{IPAddress: address})
+	if err != nil && !IsNotFoundError(err) {
+		return WrapError(err, "IP address release")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package upcloud
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+)
+
+// OSFamily groups distributions that share package management and user setup
+type OSFamily string
+
+// OS families recognised in template titles
+const (
+	OSFamilyUbuntu  OSFamily = "ubuntu"
+	OSFamilyDebian  OSFamily = "debian"
+	OSFamilyRHEL    OSFamily = "rhel"
+	OSFamilyWindows OSFamily = "windows"
+	OSFamilyUnknown OSFamily = "unknown"
+)
+
+// BootstrapFamilies lists the OS families the workspace bootstrap supports
+var BootstrapFamilies = []OSFamily{OSFamilyUbuntu, OSFamilyDebian, OSFamilyRHEL}
+
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)*`)
+
+// OSInfo describes the operating system of a template
+type OSInfo struct {
+	Family       OSFamily `json:"family" yaml:"family"`
+	Distribution string   `json:"distribution" yaml:"distribution"`
+	Version      string   `json:"version" yaml:"version"`
+}
+
+// DetectOS derives the operating system from a template title such as
+// "Ubuntu Server 24.04 LTS (Noble Numbat)" or "Rocky Linux 9"
+func DetectOS(title string) OSInfo {
+	lower := strings.ToLower(title)
+	info := OSInfo{
+		Family:  OSFamilyUnknown,
+		Version: versionPattern.FindString(title),
+	}
+
+	distributions := []struct {
+		keyword string
+		name    string
+		family  OSFamily
+	}{
+		{"ubuntu", "ubuntu", OSFamilyUbuntu},
+		{"debian", "debian", OSFamilyDebian},
+		{"rocky", "rocky", OSFamilyRHEL},
+		{"alma", "almalinux", OSFamilyRHEL},
+		{"centos", "centos", OSFamilyRHEL},
+		{"red hat", "rhel", OSFamilyRHEL},
+		{"rhel", "rhel", OSFamilyRHEL},
+		{"windows", "windows", OSFamilyWindows},
+	}
+	for _, d := range distributions {
+		if strings.Contains(lower, d.keyword) {
+			info.Family = d.family
+			info.Distribution = d.name
+			break
+		}
+	}
+
+	return info
+}
+
+// DetectTemplateOS returns the operating system of a template, preferring the
+// labels set by 'image build' over the title
+func DetectTemplateOS(template *TemplateInfo) OSInfo {
+	if family, ok := template.Labels[LabelOSFamily]; ok {
+		info := DetectOS(template.Title)
+		info.Family = OSFamily(family)
+		if version, ok := template.Labels[LabelOSVersion]; ok {
+			info.Version = version
+		}
+		return info
+	}
+
+	return DetectOS(template.Title)
+}
+
+// IsBootstrapSupported checks if the workspace bootstrap can provision a
+// template. Only cloud-init templates run user data at all.
+func IsBootstrapSupported(template *TemplateInfo) bool {
+	if template.TemplateType != upcloud.StorageTemplateTypeCloudInit {
+		return false
+	}
+
+	family := DetectTemplateOS(template).Family
+	for _, supported := range BootstrapFamilies {
+		if family == supported {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package upcloud
+
+import (
+	"testing"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+)
+
+func TestDetectOS(t *testing.T) {
+	tests := []struct {
+		title string
+		want  OSInfo
+	}{
+		{"Ubuntu Server 24.04 LTS (Noble Numbat)", OSInfo{OSFamilyUbuntu, "ubuntu", "24.04"}},
+		{"Debian GNU/Linux 12 (Bookworm)", OSInfo{OSFamilyDebian, "debian", "12"}},
+		{"Rocky Linux 9", OSInfo{OSFamilyRHEL, "rocky", "9"}},
+		{"AlmaLinux 9", OSInfo{OSFamilyRHEL, "almalinux", "9"}},
+		{"CentOS Stream 9", OSInfo{OSFamilyRHEL, "centos", "9"}},
+		{"Red Hat Enterprise Linux 9.4", OSInfo{OSFamilyRHEL, "rhel", "9.4"}},
+		{"RHEL 8", OSInfo{OSFamilyRHEL, "rhel", "8"}},
+		{"Windows Server 2022 Standard", OSInfo{OSFamilyWindows, "windows", "2022"}},
+		{"FreeBSD 14.1", OSInfo{OSFamilyUnknown, "", "14.1"}},
+		{"devpod-workspace", OSInfo{OSFamilyUnknown, "", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := DetectOS(tt.title); got != tt.want {
+				t.Errorf("DetectOS(%q) = %+v, want %+v", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectTemplateOS(t *testing.T) {
+	template := &TemplateInfo{
+		Title:  "devpod-workspace 2026",
+		Labels: map[string]string{LabelOSFamily: string(OSFamilyDebian), LabelOSVersion: "12"},
+	}
+
+	// Labels set by 'image build' win over the title
+	want := OSInfo{Family: OSFamilyDebian, Version: "12"}
+	if got := DetectTemplateOS(template); got != want {
+		t.Errorf("DetectTemplateOS() = %+v, want %+v", got, want)
+	}
+}
+
+func TestIsBootstrapSupported(t *testing.T) {
+	tests := []struct {
+		name     string
+		template TemplateInfo
+		want     bool
+	}{
+		{"Ubuntu", TemplateInfo{Title: "Ubuntu Server 24.04 LTS (Noble Numbat)", TemplateType: upcloud.StorageTemplateTypeCloudInit}, true},
+		{"Debian", TemplateInfo{Title: "Debian GNU/Linux 12 (Bookworm)", TemplateType: upcloud.StorageTemplateTypeCloudInit}, true},
+		{"RHEL-alike", TemplateInfo{Title: "AlmaLinux 9", TemplateType: upcloud.StorageTemplateTypeCloudInit}, true},
+		{"Without cloud-init", TemplateInfo{Title: "Ubuntu Server 20.04 LTS (Focal Fossa)", TemplateType: upcloud.StorageTemplateTypeNative}, false},
+		{"Windows", TemplateInfo{Title: "Windows Server 2022 Standard", TemplateType: upcloud.StorageTemplateTypeCloudInit}, false},
+		{"Unknown title", TemplateInfo{Title: "devpod-workspace", TemplateType: upcloud.StorageTemplateTypeCloudInit}, false},
+		{"Unknown title with a family label", TemplateInfo{
+			Title:        "devpod-workspace",
+			TemplateType: upcloud.StorageTemplateTypeCloudInit,
+			Labels:       map[string]string{LabelOSFamily: string(OSFamilyUbuntu)},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBootstrapSupported(&tt.template); got != tt.want {
+				t.Errorf("IsBootstrapSupported(%q) = %v, want %v", tt.template.Title, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package upcloud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+)
+
+// PreflightResult collects the problems found while validating a server configuration
+type PreflightResult struct {
+	Errors   []string
+	Warnings []string
+	// Template is the template the server will be created from, or nil if
+	// it could not be resolved
+	Template *TemplateInfo
+}
+
+func (r *PreflightResult) addError(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+func (r *PreflightResult) addWarning(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Err returns all collected errors as a single error, or nil if there are none
+func (r *PreflightResult) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+
+	return &ProviderError{
+		Type:    ErrorTypeInvalidParameter,
+		Message: fmt.Sprintf("Preflight checks failed:\n  - %s", strings.Join(r.Errors, "\n  - ")),
+	}
+}
+
+// Preflight validates a server configuration against the plan catalog, the
+// target zone and the account before anything is created. Every problem is
+// collected instead of stopping at the first one; checks that cannot be
+// completed because of API errors are reported as warnings.
+func (c *Client) Preflight(ctx context.Context, serverConfig *ServerConfig) *PreflightResult {
+	result := &PreflightResult{}
+
+	if err := ValidateZone(serverConfig.Zone); err != nil {
+		result.addError("%v", err)
+	}
+
+	plan, err := MapPlanName(serverConfig.Plan)
+	if err != nil {
+		result.addError("%v", err)
+	}
+
+	storageSize, err := ParseStorageSize(serverConfig.Storage)
+	if err != nil {
+		result.addError("%v", err)
+	}
+
+	result.Template = serverConfig.ResolvedTemplate
+	if result.Template == nil {
+		result.Template, err = c.ResolveTemplate(ctx, serverConfig.Image, serverConfig.Template, serverConfig.Zone)
+		if err != nil {
+			result.addError("%v", err)
+		}
+	}
+
+	plans, err := config.LoadServerPlans()
+	if err != nil {
+		result.addWarning("could not load plan catalog: %v", err)
+	} else {
+		checkStorageAgainstPlan(result, plans, plan, storageSize)
+		if serverConfig.Encrypted && !plans.SupportsEncryption(serverConfig.Zone) {
+			result.addWarning("storage encryption is not listed for zone %s in the plan catalog; create fails if UpCloud does not offer it there",
+				serverConfig.Zone)
+		}
+	}
+
+	// Everything below needs the UpCloud API
+	if c.service == nil {
+		return result
+	}
+
+	if result.Template != nil {
+		checkTemplateSize(result, result.Template, storageSize)
+	}
+
+	if plan != "" {
+		c.checkPlanInZone(ctx, result, plan, serverConfig.Zone)
+		c.checkPlanTier(ctx, result, plan)
+		if plans != nil {
+			c.checkPlanLimit(ctx, result, plans, plan)
+		}
+	}
+
+	if serverConfig.Encrypted && result.Template != nil {
+		if err := c.validateEncryption(ctx, result.Template.UUID); err != nil {
+			result.addError("%v", err)
+		}
+	}
+
+	return result
+}
+
+// checkStorageAgainstPlan compares the requested storage with the catalog minimum and the plan's included storage
+func checkStorageAgainstPlan(result *PreflightResult, plans *config.ServerPlans, plan string, storageSize int) {
+	if storageSize == 0 {
+		return
+	}
+
+	minimum := plans.SelectionRules.Minimum.Storage
+	if storageSize < minimum {
+		result.addError("storage size %d GB is below the DevPod minimum of %d GB", storageSize, minimum)
+	}
+
+	planSpec, _, err := plans.GetPlanByID(plan)
+	if err != nil {
+		return
+	}
+	if planSpec.Storage > 0 && storageSize > planSpec.Storage {
+		result.addWarning("storage size %d GB exceeds the %d GB included in plan %s; the difference is billed separately",
+			storageSize, planSpec.Storage, plan)
+	}
+}
+
+// checkTemplate verifies the template is usable in the zone and fits into the requested storage
+func (c *Client) checkTemplate(ctx context.Context, result *PreflightResult, templateUUID, zone string, storageSize int) {
+	template, err := c.ValidateTemplate(ctx, templateUUID, zone)
+	if err != nil {
+		if perr, ok := err.(*ProviderError); ok && perr.Type == ErrorTypeInvalidParameter {
+			result.addError("%v", err)
+			return
+		}
+		result.addWarning("could not check template %s: %v", templateUUID, err)
+		return
+	}
+
+	checkTemplateSize(result, template, storageSize)
+}
+
+// checkTemplateSize verifies the template fits into the requested storage
+func checkTemplateSize(result *PreflightResult, template *TemplateInfo, storageSize int) {
+	if storageSize > 0 && storageSize < template.Size {
+		result.addError("storage size %d GB is smaller than the %d GB required by template %s", storageSize, template.Size, template.Title)
+	}
+}
+
+// checkPlanInZone verifies the plan is sold in the target zone
+func (c *Client) checkPlanInZone(ctx context.Context, result *PreflightResult, plan, zone string) {
+	prices, err := c.GetZonePrices(ctx)
+	if err != nil {
+		result.addWarning("could not check plan availability: %v", err)
+		return
+	}
+
+	zonePrices, ok := prices[zone]
+	if !ok {
+		// Unknown zones are already reported by zone validation
+		return
+	}
+	if !zonePrices.HasPlan(plan) {
+		result.addError("plan %s is not available in zone %s", plan, zone)
+	}
+}
+
+// checkPlanTier verifies UpCloud accepts the storage tier the provider picks for the plan
+func (c *Client) checkPlanTier(ctx context.Context, result *PreflightResult, plan string) {
+	livePlans, err := c.service.GetPlans(ctx)
+	if err != nil {
+		result.addWarning("could not check plan storage tier: %v", WrapError(err, "listing plans"))
+		return
+	}
+
+	for _, livePlan := range livePlans.Plans {
+		if livePlan.Name != plan {
+			continue
+		}
+		tier := GetStorageTier(plan)
+		if livePlan.StorageTier != "" && livePlan.StorageTier != tier {
+			result.addError("plan %s comes with %s storage, but the provider would create %s storage for it", plan, livePlan.StorageTier, tier)
+		}
+		return
+	}
+
+	result.addError("plan %s is not offered by UpCloud", plan)
+}
+
+// checkPlanLimit enforces the per-account server limit of restricted plans
+func (c *Client) checkPlanLimit(ctx context.Context, result *PreflightResult, plans *config.ServerPlans, plan string) {
+	planSpec, _, err := plans.GetPlanByID(plan)
+	if err != nil || planSpec.Restrictions == nil || planSpec.Restrictions.MaxPerAccount == 0 {
+		return
+	}
+
+	servers, err := c.service.GetServers(ctx)
+	if err != nil {
+		result.addWarning("could not check per-account limit for plan %s: %v", plan, WrapError(err, "listing servers"))
+		return
+	}
+
+	count := 0
+	for _, server := range servers.Servers {
+		if server.Plan == plan {
+			count++
+		}
+	}
+
+	if count >= planSpec.Restrictions.MaxPerAccount {
+		result.addError("plan %s is limited to %d servers per account and %d already exist",
+			plan, planSpec.Restrictions.MaxPerAccount, count)
+	}
+}
@@ -0,0 +1,205 @@
+package upcloud
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+)
+
+func TestCheckStorageAgainstPlan(t *testing.T) {
+	plans := &config.ServerPlans{
+		Categories: map[string]*config.PlanCategory{
+			"developer":    {Plans: []config.ServerPlan{{ID: "DEV-2xCPU-4GB", Storage: 60}}},
+			"cloud_native": {Plans: []config.ServerPlan{{ID: "CN-2xCPU-4GB", Storage: 0}}},
+		},
+	}
+	plans.SelectionRules.Minimum.Storage = 10
+
+	tests := []struct {
+		name         string
+		plan         string
+		storageSize  int
+		wantErrors   int
+		wantWarnings int
+	}{
+		{"Within included storage", "DEV-2xCPU-4GB", 50, 0, 0},
+		{"Exactly included storage", "DEV-2xCPU-4GB", 60, 0, 0},
+		{"Beyond included storage", "DEV-2xCPU-4GB", 100, 0, 1},
+		{"Below minimum", "DEV-2xCPU-4GB", 5, 1, 0},
+		{"Plan without included storage", "CN-2xCPU-4GB", 500, 0, 0},
+		{"Unknown plan only checks the minimum", "UNKNOWN-PLAN", 5, 1, 0},
+		{"Unknown plan above minimum", "UNKNOWN-PLAN", 100, 0, 0},
+		{"Unparsed storage is skipped", "DEV-2xCPU-4GB", 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &PreflightResult{}
+			checkStorageAgainstPlan(result, plans, tt.plan, tt.storageSize)
+			if len(result.Errors) != tt.wantErrors || len(result.Warnings) != tt.wantWarnings {
+				t.Errorf("got errors %v and warnings %v, want %d errors and %d warnings",
+					result.Errors, result.Warnings, tt.wantErrors, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestPreflightResultErr(t *testing.T) {
+	tests := []struct {
+		name   string
+		result PreflightResult
+		want   string
+	}{
+		{"No problems", PreflightResult{}, ""},
+		{"Warnings only", PreflightResult{Warnings: []string{"storage billed separately"}}, ""},
+		{"Single error", PreflightResult{Errors: []string{"invalid zone: xx-foo1"}},
+			"Preflight checks failed:\n  - invalid zone: xx-foo1"},
+		{"Several errors", PreflightResult{Errors: []string{"invalid zone: xx-foo1", "unknown plan FOO"}},
+			"Preflight checks failed:\n  - invalid zone: xx-foo1\n  - unknown plan FOO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.result.Err()
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Err() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Err() = %v, want %q", err, tt.want)
+			}
+			if perr, ok := err.(*ProviderError); !ok || perr.Type != ErrorTypeInvalidParameter {
+				t.Errorf("Err() = %#v, want an invalid parameter ProviderError", err)
+			}
+		})
+	}
+}
+
+func TestPreflightCollectsAllErrors(t *testing.T) {
+	client := NewUpCloud("test", "test")
+
+	result := client.Preflight(context.Background(), &ServerConfig{
+		Zone:    "xx-foo1",
+		Plan:    "NOT-A-PLAN",
+		Storage: "5",
+		Image:   "Ubuntu Server 24.04 LTS (Noble Numbat)",
+	})
+
+	if len(result.Errors) != 3 {
+		t.Fatalf("got errors %v, want zone, plan and storage errors", result.Errors)
+	}
+	for i, want := range []string{"xx-foo1", "NOT-A-PLAN", "storage size"} {
+		if !strings.Contains(result.Errors[i], want) {
+			t.Errorf("error %d = %q, want it to mention %q", i, result.Errors[i], want)
+		}
+	}
+
+	err := result.Err()
+	if err == nil {
+		t.Fatal("Err() = nil, want the collected errors")
+	}
+	if got := strings.Count(err.Error(), "\n  - "); got != 3 {
+		t.Errorf("Err() reports %d problems, want 3: %v", got, err)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("Warnings = %v, want none", result.Warnings)
+	}
+}
+
+func TestPreflightReportsTemplateWithOtherErrors(t *testing.T) {
+	client := NewUpCloud("test", "test")
+
+	result := client.Preflight(context.Background(), &ServerConfig{
+		Zone:     "xx-foo1",
+		Plan:     "DEV-2xCPU-4GB",
+		Storage:  "50",
+		Template: "not-a-uuid",
+	})
+
+	if len(result.Errors) != 2 || !strings.Contains(result.Errors[0], "xx-foo1") || !strings.Contains(result.Errors[1], "not-a-uuid") {
+		t.Errorf("got errors %v, want zone and template errors", result.Errors)
+	}
+	if result.Template != nil {
+		t.Errorf("Template = %+v, want nil for an invalid template", result.Template)
+	}
+}
+
+func TestPreflightResolvesTemplate(t *testing.T) {
+	client := NewUpCloud("test", "test")
+
+	result := client.Preflight(context.Background(), &ServerConfig{
+		Zone:    "de-fra1",
+		Plan:    "DEV-2xCPU-4GB",
+		Storage: "50",
+		Image:   "Ubuntu Server 24.04 LTS (Noble Numbat)",
+	})
+
+	if err := result.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Template == nil || result.Template.UUID == "" {
+		t.Errorf("Template = %+v, want the resolved image template", result.Template)
+	}
+}
+
+func TestPreflightWarnsAboutEncryptionZone(t *testing.T) {
+	client := NewUpCloud("test", "test")
+
+	tests := []struct {
+		zone        string
+		wantWarning bool
+	}{
+		{"de-fra1", false},
+		{"us-nyc1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			result := client.Preflight(context.Background(), &ServerConfig{
+				Zone:      tt.zone,
+				Plan:      "DEV-2xCPU-4GB",
+				Storage:   "50",
+				Image:     "Ubuntu Server 24.04 LTS (Noble Numbat)",
+				Encrypted: true,
+			})
+
+			// A zone missing from the catalog is left to the API
+			if err := result.Err(); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			warned := len(result.Warnings) == 1 && strings.Contains(result.Warnings[0], tt.zone)
+			if warned != tt.wantWarning {
+				t.Errorf("Warnings = %v, want encryption warning %v", result.Warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestCheckTemplateSize(t *testing.T) {
+	template := &TemplateInfo{UUID: "01000000-0000-4000-8000-000030240200", Title: "Ubuntu Server 24.04 LTS", Size: 10}
+
+	tests := []struct {
+		name        string
+		storageSize int
+		wantErrors  int
+	}{
+		{"Larger than the template", 50, 0},
+		{"Exactly the template size", 10, 0},
+		{"Smaller than the template", 5, 1},
+		{"Unparsed storage is skipped", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &PreflightResult{}
+			checkTemplateSize(result, template, tt.storageSize)
+			if len(result.Errors) != tt.wantErrors {
+				t.Errorf("got errors %v, want %d", result.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package upcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+)
+
+// Price list items
+const (
+	// planPricePrefix prefixes server plan items in the UpCloud price list
+	planPricePrefix = "server_plan_"
+	// storagePricePrefix prefixes storage tier items, priced per GB
+	storagePricePrefix = "storage_"
+	// ipv4PriceItem is the price of a public IPv4 address
+	ipv4PriceItem = "ipv4_address"
+)
+
+// HoursPerMonth is the number of hours UpCloud bills at most in a month
+const HoursPerMonth = 672
+
+// ZonePrices maps price item names, such as "server_plan_DEV-2xCPU-4GB" or
+// "storage_maxiops", to their price in a single zone. Prices are in euro
+// cents per hour for the item's amount.
+type ZonePrices map[string]upcloud.Price
+
+// HasPlan checks if a server plan is sold in the zone
+func (z ZonePrices) HasPlan(plan string) bool {
+	_, ok := z[planPricePrefix+plan]
+	return ok
+}
+
+// Plans returns the server plans sold in the zone
+func (z ZonePrices) Plans() []string {
+	var plans []string
+	for item := range z {
+		if strings.HasPrefix(item, planPricePrefix) {
+			plans = append(plans, strings.TrimPrefix(item, planPricePrefix))
+		}
+	}
+	return plans
+}
+
+// MonthlyCost returns the monthly price in euro of quantity units of a price
+// item, or zero if the zone does not sell it
+func (z ZonePrices) MonthlyCost(item string, quantity int) float64 {
+	price, ok := z[item]
+	if !ok || price.Amount == 0 {
+		return 0
+	}
+	return price.Price / 100 / float64(price.Amount) * float64(quantity) * HoursPerMonth
+}
+
+// MonthlyPlanCost returns the monthly price in euro of a server plan
+func (z ZonePrices) MonthlyPlanCost(plan string) float64 {
+	return z.MonthlyCost(planPricePrefix+plan, 1)
+}
+
+// MonthlyStorageCost returns the monthly price in euro of a storage of the
+// given tier and size in GB
+func (z ZonePrices) MonthlyStorageCost(tier string, size int) float64 {
+	return z.MonthlyCost(storagePricePrefix+tier, size)
+}
+
+// MonthlyIPv4Cost returns the monthly price in euro of a public IPv4 address
+func (z ZonePrices) MonthlyIPv4Cost() float64 {
+	return z.MonthlyCost(ipv4PriceItem, 1)
+}
+
+// GetZonePrices returns the price list of every zone, keyed by zone ID.
+// The typed SDK response only covers a fixed set of legacy plans, so the raw
+// price list is decoded instead.
+func (c *Client) GetZonePrices(ctx context.Context) (map[string]ZonePrices, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating price list retrieval\n")
+		return map[string]ZonePrices{}, nil
+	}
+
+	body, err := c.api.Get(ctx, "/price")
+	if err != nil {
+		return nil, WrapError(err, "fetching prices")
+	}
+
+	var response struct {
+		Prices struct {
+			Zone []map[string]json.RawMessage `json:"zone"`
+		} `json:"prices"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, WrapError(err, "parsing prices")
+	}
+
+	zones := make(map[string]ZonePrices, len(response.Prices.Zone))
+	for _, zone := range response.Prices.Zone {
+		var name string
+		if err := json.Unmarshal(zone["name"], &name); err != nil || name == "" {
+			continue
+		}
+
+		prices := ZonePrices{}
+		for item, raw := range zone {
+			var price upcloud.Price
+			if item == "name" || json.Unmarshal(raw, &price) != nil {
+				continue
+			}
+			prices[item] = price
+		}
+		zones[name] = prices
+	}
+
+	return zones, nil
+}
+
+// GetLivePlans returns every plan UpCloud offers with its specifications and
+// its price in a zone. Plans not sold in the zone have no price.
+func (c *Client) GetLivePlans(ctx context.Context, zone string) ([]config.ServerPlan, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating plan list retrieval\n")
+		plans, err := config.LoadServerPlans()
+		if err != nil {
+			return nil, err
+		}
+		var live []config.ServerPlan
+		for _, category := range plans.Categories {
+			live = append(live, category.Plans...)
+		}
+		return live, nil
+	}
+
+	plans, err := c.service.GetPlans(ctx)
+	if err != nil {
+		return nil, WrapError(err, "listing plans")
+	}
+	prices, err := c.GetZonePrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zonePrices, ok := prices[zone]
+	if !ok {
+		return nil, &ProviderError{
+			Type:    ErrorTypeInvalidParameter,
+			Message: fmt.Sprintf("No prices found for zone %s", zone),
+			Hint:    "Run 'devpod-provider-upcloud zones' to list the zones available to the account",
+		}
+	}
+
+	live := make([]config.ServerPlan, 0, len(plans.Plans))
+	for _, plan := range plans.Plans {
+		monthly := zonePrices.MonthlyPlanCost(plan.Name)
+		live = append(live, config.ServerPlan{
+			ID:           plan.Name,
+			CPU:          plan.CoreNumber,
+			RAM:          plan.MemoryAmount,
+			Storage:      plan.StorageSize,
+			PriceMonthly: float32(monthly),
+			PriceHourly:  float32(monthly / HoursPerMonth),
+		})
+	}
+	return live, nil
+}
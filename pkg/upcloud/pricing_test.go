@@ -0,0 +1,36 @@
+package upcloud
+
+import (
+	"math"
+	"testing"
+)
+
+func TestZonePricesMonthlyCost(t *testing.T) {
+	prices := ZonePrices{
+		"server_plan_DEV-2xCPU-4GB": {Amount: 1, Price: 2.6786},
+		"storage_standard":          {Amount: 1, Price: 0.0082},
+		"storage_maxiops":           {Amount: 10, Price: 0.3125},
+		"ipv4_address":              {Amount: 1, Price: 0.4464},
+	}
+
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"Plan", prices.MonthlyPlanCost("DEV-2xCPU-4GB"), 18.00},
+		{"Storage per GB", prices.MonthlyStorageCost("standard", 50), 2.76},
+		{"Storage priced per 10 GB", prices.MonthlyStorageCost("maxiops", 20), 4.20},
+		{"IPv4", prices.MonthlyIPv4Cost(), 3.00},
+		{"Unknown plan", prices.MonthlyPlanCost("DEV-1xCPU-1GB"), 0},
+		{"Unknown tier", prices.MonthlyStorageCost("hdd", 100), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if math.Abs(tt.got-tt.want) > 0.01 {
+				t.Errorf("got %.4f, want %.2f", tt.got, tt.want)
+			}
+		})
+	}
+}
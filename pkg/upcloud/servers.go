@@ -0,0 +1,277 @@
+package upcloud
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+)
+
+// ManagedServer is a workspace server created by the provider
+type ManagedServer struct {
+	ServerInfo `yaml:",inline"`
+
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	// CreatedAt is when the server was created, taken from the root storage
+	// for servers created before servers were labelled
+	CreatedAt *time.Time `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	// StartedAt is when the provider last started the server, if known
+	StartedAt *time.Time `json:"started_at,omitempty" yaml:"started_at,omitempty"`
+	// RunningHours is the running time of completed runs, excluding the current one
+	RunningHours float64 `json:"running_hours" yaml:"running_hours"`
+	// Labelled is false for servers created before the provider labelled them
+	Labelled bool `json:"labelled" yaml:"labelled"`
+}
+
+// Uptime returns how long a running server has been up since the provider
+// last started it, or zero if unknown or not running
+func (s *ManagedServer) Uptime() time.Duration {
+	if s.StartedAt == nil || s.Status != StatusRunning {
+		return 0
+	}
+	return time.Since(*s.StartedAt)
+}
+
+// TotalRunningHours returns the hours the server has been running, including
+// the current run. A run ended from inside the guest counts until the provider
+// next starts or stops the server.
+func (s *ManagedServer) TotalRunningHours() float64 {
+	hours := s.RunningHours
+	if s.StartedAt != nil {
+		hours += time.Since(*s.StartedAt).Hours()
+	}
+	return hours
+}
+
+// ListManagedServers returns the account's workspace servers in every zone:
+// those labelled by the provider and, for older workspaces, those whose title
+// starts with ManagedServerPrefix
+func (c *Client) ListManagedServers(ctx context.Context) ([]ManagedServer, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating server list\n")
+		startedAt := time.Now().Add(-time.Hour)
+		return []ManagedServer{
+			{
+				ServerInfo: ServerInfo{
+					MachineID: "devpod-test",
+					UUID:      "test-uuid",
+					Hostname:  "test",
+					Status:    StatusRunning,
+					Plan:      "DEV-2xCPU-4GB",
+					Zone:      "de-fra1",
+					IP:        "192.0.2.1",
+				},
+				Owner:     "test",
+				CreatedAt: &startedAt,
+				StartedAt: &startedAt,
+				Labelled:  true,
+			},
+		}, nil
+	}
+
+	labelled, err := c.service.GetServersWithFilters(ctx, &request.GetServersWithFiltersRequest{
+		Filters: []request.QueryFilter{
+			request.FilterLabel{Label: upcloud.Label{Key: LabelManaged, Value: "true"}},
+		},
+	})
+	if err != nil {
+		return nil, WrapError(err, "listing servers")
+	}
+	all, err := c.service.GetServers(ctx)
+	if err != nil {
+		return nil, WrapError(err, "listing servers")
+	}
+
+	candidates := map[string]bool{}
+	for _, server := range labelled.Servers {
+		candidates[server.UUID] = true
+	}
+	for _, server := range all.Servers {
+		if strings.HasPrefix(server.Title, ManagedServerPrefix) {
+			candidates[server.UUID] = true
+		}
+	}
+
+	servers := make([]ManagedServer, 0, len(candidates))
+	for uuid := range candidates {
+		details, err := c.service.GetServerDetails(ctx, &request.GetServerDetailsRequest{UUID: uuid})
+		if err != nil {
+			// Deleted while listing
+			if IsNotFoundError(err) {
+				continue
+			}
+			return nil, WrapError(err, "server details")
+		}
+		server := NewManagedServer(details)
+		if server.CreatedAt == nil {
+			server.CreatedAt = c.rootStorageCreated(ctx, details)
+		}
+		servers = append(servers, *server)
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].MachineID < servers[j].MachineID
+	})
+
+	return servers, nil
+}
+
+// GetManagedServer returns a workspace server by machine ID
+func (c *Client) GetManagedServer(ctx context.Context, machineID string) (*ManagedServer, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating server lookup for %s\n", machineID)
+		startedAt := time.Now().Add(-time.Hour)
+		return &ManagedServer{
+			ServerInfo: ServerInfo{MachineID: machineID, UUID: "test-uuid", Status: StatusRunning, IP: "192.0.2.1"},
+			StartedAt:  &startedAt,
+			Labelled:   true,
+		}, nil
+	}
+
+	server, err := c.findServerByMachineID(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+	details, err := c.service.GetServerDetails(ctx, &request.GetServerDetailsRequest{UUID: server.UUID})
+	if err != nil {
+		return nil, WrapError(err, "getting server details")
+	}
+
+	return NewManagedServer(details), nil
+}
+
+// NewManagedServer converts UpCloud server details into a ManagedServer
+func NewManagedServer(details *upcloud.ServerDetails) *ManagedServer {
+	server := &ManagedServer{
+		ServerInfo: *NewServerInfo(details),
+	}
+
+	for _, label := range details.Labels {
+		switch label.Key {
+		case LabelManaged:
+			server.Labelled = true
+		case LabelMachineID:
+			server.MachineID = label.Value
+		case LabelOwner:
+			server.Owner = label.Value
+		case LabelCreatedAt:
+			if createdAt, err := time.Parse(time.RFC3339, label.Value); err == nil {
+				server.CreatedAt = &createdAt
+			}
+		case LabelStartedAt:
+			if startedAt, err := time.Parse(time.RFC3339, label.Value); err == nil {
+				server.StartedAt = &startedAt
+			}
+		case LabelRunningHours:
+			if hours, err := strconv.ParseFloat(label.Value, 64); err == nil {
+				server.RunningHours = hours
+			}
+		}
+	}
+
+	return server
+}
+
+// rootStorageCreated returns the creation time of a server's root storage,
+// or nil if it cannot be looked up
+func (c *Client) rootStorageCreated(ctx context.Context, details *upcloud.ServerDetails) *time.Time {
+	rootStorage, err := FindRootStorage(details)
+	if err != nil {
+		return nil
+	}
+	storage, err := c.service.GetStorageDetails(ctx, &request.GetStorageDetailsRequest{UUID: rootStorage.UUID})
+	if err != nil || storage.Created.IsZero() {
+		return nil
+	}
+	return &storage.Created
+}
+
+// startRun records the start of a new run. A run left open by a stop from
+// inside the guest is closed first, so its hours are counted up to now.
+func (c *Client) startRun(ctx context.Context, uuid string) error {
+	return c.updateServerLabels(ctx, uuid, func(labels upcloud.LabelSlice) upcloud.LabelSlice {
+		return startRunLabels(labels, time.Now())
+	})
+}
+
+// closeRun adds the time since the last start to the running hours and
+// clears the start time, so a stopped server no longer accrues running hours
+func (c *Client) closeRun(ctx context.Context, uuid string) error {
+	return c.updateServerLabels(ctx, uuid, func(labels upcloud.LabelSlice) upcloud.LabelSlice {
+		return closeRunLabels(labels, time.Now())
+	})
+}
+
+// startRunLabels returns labels with any open run closed and a new one started at now
+func startRunLabels(labels upcloud.LabelSlice, now time.Time) upcloud.LabelSlice {
+	if closed := closeRunLabels(labels, now); closed != nil {
+		labels = closed
+	}
+	return append(withoutLabels(labels, LabelStartedAt),
+		upcloud.Label{Key: LabelStartedAt, Value: now.UTC().Format(time.RFC3339)})
+}
+
+// closeRunLabels returns labels with the run since the start time added to the
+// running hours and the start time removed, or nil if no run is open
+func closeRunLabels(labels upcloud.LabelSlice, now time.Time) upcloud.LabelSlice {
+	var startedAt time.Time
+	var hours float64
+	for _, label := range labels {
+		switch label.Key {
+		case LabelStartedAt:
+			startedAt, _ = time.Parse(time.RFC3339, label.Value)
+		case LabelRunningHours:
+			hours, _ = strconv.ParseFloat(label.Value, 64)
+		}
+	}
+	if startedAt.IsZero() {
+		return nil
+	}
+
+	hours += now.Sub(startedAt).Hours()
+	return append(withoutLabels(labels, LabelStartedAt, LabelRunningHours),
+		upcloud.Label{Key: LabelRunningHours, Value: strconv.FormatFloat(hours, 'f', 2, 64)})
+}
+
+// updateServerLabels replaces a server's labels with the result of update,
+// leaving them untouched if update returns nil
+func (c *Client) updateServerLabels(ctx context.Context, uuid string, update func(upcloud.LabelSlice) upcloud.LabelSlice) error {
+	details, err := c.service.GetServerDetails(ctx, &request.GetServerDetailsRequest{UUID: uuid})
+	if err != nil {
+		return WrapError(err, "server details")
+	}
+
+	labels := update(details.Labels)
+	if labels == nil {
+		return nil
+	}
+
+	_, err = c.service.ModifyServer(ctx, &request.ModifyServerRequest{
+		UUID:   uuid,
+		Labels: &labels,
+	})
+	if err != nil {
+		return WrapError(err, "server labels")
+	}
+	return nil
+}
+
+// withoutLabels returns a copy of labels without the given keys
+func withoutLabels(labels upcloud.LabelSlice, keys ...string) upcloud.LabelSlice {
+	kept := upcloud.LabelSlice{}
+	for _, label := range labels {
+		if !slices.Contains(keys, label.Key) {
+			kept = append(kept, label)
+		}
+	}
+	return kept
+}
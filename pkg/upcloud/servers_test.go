@@ -0,0 +1,248 @@
+package upcloud
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+)
+
+func TestNewManagedServer(t *testing.T) {
+	tests := []struct {
+		name          string
+		title         string
+		labels        upcloud.LabelSlice
+		wantMachineID string
+		wantOwner     string
+		wantStarted   bool
+		wantLabelled  bool
+	}{
+		{
+			name:  "Labelled",
+			title: "devpod-title",
+			labels: upcloud.LabelSlice{
+				{Key: LabelManaged, Value: "true"},
+				{Key: LabelMachineID, Value: "devpod-workspace"},
+				{Key: LabelOwner, Value: "alice"},
+				{Key: LabelStartedAt, Value: "2026-01-02T03:04:05Z"},
+			},
+			wantMachineID: "devpod-workspace",
+			wantOwner:     "alice",
+			wantStarted:   true,
+			wantLabelled:  true,
+		},
+		{
+			name:          "Created before labels",
+			title:         "devpod-legacy",
+			wantMachineID: "devpod-legacy",
+		},
+		{
+			name:  "Invalid start time",
+			title: "devpod-workspace",
+			labels: upcloud.LabelSlice{
+				{Key: LabelManaged, Value: "true"},
+				{Key: LabelStartedAt, Value: "yesterday"},
+			},
+			wantMachineID: "devpod-workspace",
+			wantLabelled:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := &upcloud.ServerDetails{
+				Server: upcloud.Server{
+					Title: tt.title,
+					State: upcloud.ServerStateStarted,
+				},
+				Labels: tt.labels,
+			}
+
+			got := NewManagedServer(details)
+			if got.MachineID != tt.wantMachineID {
+				t.Errorf("MachineID = %q, want %q", got.MachineID, tt.wantMachineID)
+			}
+			if got.Owner != tt.wantOwner {
+				t.Errorf("Owner = %q, want %q", got.Owner, tt.wantOwner)
+			}
+			if (got.StartedAt != nil) != tt.wantStarted {
+				t.Errorf("StartedAt = %v, want set %v", got.StartedAt, tt.wantStarted)
+			}
+			if got.Labelled != tt.wantLabelled {
+				t.Errorf("Labelled = %v, want %v", got.Labelled, tt.wantLabelled)
+			}
+			if got.Status != StatusRunning {
+				t.Errorf("Status = %q, want %q", got.Status, StatusRunning)
+			}
+		})
+	}
+}
+
+func TestManagedServerUptime(t *testing.T) {
+	startedAt := time.Now().Add(-2 * time.Hour)
+
+	running := &ManagedServer{ServerInfo: ServerInfo{Status: StatusRunning}, StartedAt: &startedAt}
+	if uptime := running.Uptime(); uptime < 2*time.Hour {
+		t.Errorf("Uptime() = %v, want at least 2h", uptime)
+	}
+
+	stopped := &ManagedServer{ServerInfo: ServerInfo{Status: StatusStopped}, StartedAt: &startedAt}
+	if uptime := stopped.Uptime(); uptime != 0 {
+		t.Errorf("Uptime() of a stopped server = %v, want 0", uptime)
+	}
+
+	unknown := &ManagedServer{ServerInfo: ServerInfo{Status: StatusRunning}}
+	if uptime := unknown.Uptime(); uptime != 0 {
+		t.Errorf("Uptime() without a start time = %v, want 0", uptime)
+	}
+}
+
+// labelValue returns the value of a label, or an empty string
+func labelValue(labels upcloud.LabelSlice, key string) string {
+	for _, label := range labels {
+		if label.Key == key {
+			return label.Value
+		}
+	}
+	return ""
+}
+
+func TestRunLabelsStopInGuest(t *testing.T) {
+	start := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	labels := upcloud.LabelSlice{{Key: LabelManaged, Value: "true"}}
+
+	// Started through the provider
+	labels = startRunLabels(labels, start)
+	if got := labelValue(labels, LabelStartedAt); got != "2026-10-18T08:00:00Z" {
+		t.Fatalf("started at = %q after the first start", got)
+	}
+
+	// Stopped from inside the guest after 2 hours, which the provider does not
+	// see, then started again through the provider 3 hours later
+	labels = startRunLabels(labels, start.Add(5*time.Hour))
+	if got := labelValue(labels, LabelRunningHours); got != "5.00" {
+		t.Errorf("running hours = %q after restarting, want the open run counted as 5.00", got)
+	}
+	if got := labelValue(labels, LabelStartedAt); got != "2026-10-18T13:00:00Z" {
+		t.Errorf("started at = %q after restarting, want the new start", got)
+	}
+
+	// Stopped through the provider an hour later
+	labels = closeRunLabels(labels, start.Add(6*time.Hour))
+	if got := labelValue(labels, LabelRunningHours); got != "6.00" {
+		t.Errorf("running hours = %q after stopping, want 6.00", got)
+	}
+	if got := labelValue(labels, LabelStartedAt); got != "" {
+		t.Errorf("started at = %q after stopping, want it cleared", got)
+	}
+	if got := labelValue(labels, LabelManaged); got != "true" {
+		t.Errorf("managed label = %q, want it kept", got)
+	}
+
+	// Stopping again does not count anything
+	if closed := closeRunLabels(labels, start.Add(7*time.Hour)); closed != nil {
+		t.Errorf("closeRunLabels() without an open run = %v, want nil", closed)
+	}
+}
+
+func TestStopServerClosesRun(t *testing.T) {
+	fake := newFakeService()
+	server := fake.addServer("server-uuid", "devpod-workspace", upcloud.ServerStateStarted, "root-uuid")
+	server.Labels = upcloud.LabelSlice{
+		{Key: LabelStartedAt, Value: time.Now().Add(-3 * time.Hour).UTC().Format(time.RFC3339)},
+		{Key: LabelRunningHours, Value: "1.00"},
+	}
+
+	if err := fake.client().stopServer(context.Background(), "server-uuid"); err != nil {
+		t.Fatalf("stopServer() error = %v", err)
+	}
+
+	if server.State != upcloud.ServerStateStopped {
+		t.Errorf("State = %s, want stopped", server.State)
+	}
+	if got := labelValue(server.Labels, LabelRunningHours); got != "4.00" {
+		t.Errorf("running hours = %q, want 4.00", got)
+	}
+	if got := labelValue(server.Labels, LabelStartedAt); got != "" {
+		t.Errorf("started at = %q, want it cleared", got)
+	}
+}
+
+func TestStartServerRecordsStartAfterWaiting(t *testing.T) {
+	fake := newFakeService()
+	server := fake.addServer("server-uuid", "devpod-workspace", upcloud.ServerStateStopped, "root-uuid")
+
+	before := time.Now().Add(-time.Second)
+	if err := fake.client().startServer(context.Background(), "server-uuid"); err != nil {
+		t.Fatalf("startServer() error = %v", err)
+	}
+
+	// The fake rejects label changes while the server is in maintenance
+	startedAt, err := time.Parse(time.RFC3339, labelValue(server.Labels, LabelStartedAt))
+	if err != nil || startedAt.Before(before.Truncate(time.Second)) {
+		t.Errorf("started at = %q, want the time of the start", labelValue(server.Labels, LabelStartedAt))
+	}
+	want := []string{"StartServer", "WaitForServerState", "GetServerDetails", "ModifyServer"}
+	if strings.Join(fake.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("calls = %v, want %v", fake.calls, want)
+	}
+}
+
+func TestStartServerIgnoresLabelErrors(t *testing.T) {
+	fake := newFakeService()
+	fake.addServer("server-uuid", "devpod-workspace", upcloud.ServerStateStopped, "root-uuid")
+	fake.failNext("ModifyServer", &upcloud.Problem{Type: "SERVER_STATE_ILLEGAL", Status: 409})
+
+	if err := fake.client().startServer(context.Background(), "server-uuid"); err != nil {
+		t.Errorf("startServer() error = %v, want label errors ignored", err)
+	}
+}
+
+func TestStopServerToleratesServersAlreadyStopping(t *testing.T) {
+	stopErr := errors.New("SERVER_STATE_ILLEGAL")
+
+	tests := []struct {
+		name       string
+		state      string
+		stopErrs   []error
+		detailsErr error
+		wantErr    bool
+	}{
+		{name: "Started", state: upcloud.ServerStateStarted},
+		{name: "Shutting down from inside the guest", state: upcloud.ServerStateMaintenance, stopErrs: []error{stopErr}},
+		{name: "Already stopped", state: upcloud.ServerStateStopped},
+		{name: "Stop fails on a started server", state: upcloud.ServerStateStarted, stopErrs: []error{stopErr}, wantErr: true},
+		{name: "State unknown after a failed stop", state: upcloud.ServerStateMaintenance, stopErrs: []error{stopErr}, detailsErr: errors.New("timeout"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeService()
+			fake.addServer("server-uuid", "devpod-workspace", tt.state, "disk-uuid")
+			fake.failNext("StopServer", tt.stopErrs...)
+			if tt.detailsErr != nil {
+				fake.failNext("GetServerDetails", tt.detailsErr)
+			}
+
+			err := fake.client().stopServer(context.Background(), "server-uuid")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "server stop") {
+					t.Errorf("stopServer() error = %v, want the stop error", err)
+				}
+				if state := fake.servers["server-uuid"].State; state != tt.state {
+					t.Errorf("server state = %s, want it unchanged at %s", state, tt.state)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("stopServer() error = %v", err)
+			}
+			if state := fake.servers["server-uuid"].State; state != upcloud.ServerStateStopped {
+				t.Errorf("server state = %s, want stopped", state)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package upcloud
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+)
+
+// ResizeStorage grows the root storage of a server to the given size in GB.
+// UpCloud resizes attached storages online where it can; if the API refuses
+// while the server is running, for whatever reason, the server is stopped for
+// the resize and started again afterwards, also when the resize still fails.
+func (c *Client) ResizeStorage(ctx context.Context, serverID string, size int) error {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating storage resize for %s to %d GB\n", serverID, size)
+		return nil
+	}
+
+	// Find the server by machine ID
+	server, err := c.findServerByMachineID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+
+	// Get full server details to locate the root storage
+	serverDetails, rootStorage, err := c.getRootStorage(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	// Storages can only grow
+	if size <= rootStorage.Size {
+		return &ProviderError{
+			Type:    ErrorTypeInvalidParameter,
+			Message: fmt.Sprintf("New size %d GB must be larger than the current size of %d GB", size, rootStorage.Size),
+		}
+	}
+
+	modifyReq := &request.ModifyStorageRequest{
+		UUID: rootStorage.UUID,
+		Size: size,
+	}
+	_, err = c.service.ModifyStorage(ctx, modifyReq)
+	if err == nil {
+		return c.waitForStorageOnline(ctx, rootStorage.UUID)
+	}
+
+	if serverDetails.State != upcloud.ServerStateStarted {
+		return WrapError(err, "storage resize")
+	}
+
+	// UpCloud reports storages it cannot resize while attached to a running
+	// server in more ways than a busy error, so retry with the server stopped
+	if err := c.stopServer(ctx, server.UUID); err != nil {
+		return err
+	}
+
+	_, err = c.service.ModifyStorage(ctx, modifyReq)
+	if err != nil {
+		// Bring the workspace back even though the resize failed
+		_ = c.startServer(ctx, server.UUID)
+		return WrapError(err, "storage resize")
+	}
+
+	if err := c.waitForStorageOnline(ctx, rootStorage.UUID); err != nil {
+		return err
+	}
+
+	return c.startServer(ctx, server.UUID)
+}
+
+// waitForStorageOnline waits until a storage is back in the online state
+func (c *Client) waitForStorageOnline(ctx context.Context, uuid string) error {
+	waitReq := &request.WaitForStorageStateRequest{
+		UUID:         uuid,
+		DesiredState: upcloud.StorageStateOnline,
+	}
+	_, err := c.service.WaitForStorageState(ctx, waitReq)
+	if err != nil {
+		return WrapError(err, "waiting for storage to come online")
+	}
+
+	return nil
+}
+
+// getRootStorage returns the server details and root storage of a server
+func (c *Client) getRootStorage(ctx context.Context, server *upcloud.Server) (*upcloud.ServerDetails, *upcloud.ServerStorageDevice, error) {
+	serverDetails, err := c.service.GetServerDetails(ctx, &request.GetServerDetailsRequest{
+		UUID: server.UUID,
+	})
+	if err != nil {
+		return nil, nil, WrapError(err, "getting server details")
+	}
+
+	rootStorage, err := FindRootStorage(serverDetails)
+	if err != nil {
+		return nil, nil, WrapError(err, "locating root storage")
+	}
+
+	return serverDetails, rootStorage, nil
+}
+
+// CreateTemplate stops a server and turns its root storage into a private
+// template with the given labels, returning the UUID of the new template
+func (c *Client) CreateTemplate(ctx context.Context, serverID string, title string, labels map[string]string) (string, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Simulating template creation from %s\n", serverID)
+		return "01000000-0000-4000-8000-000000000000", nil
+	}
+
+	// Find the server by machine ID
+	server, err := c.findServerByMachineID(ctx, serverID)
+	if err != nil {
+		return "", err
+	}
+
+	// Storages can only be templatized while detached or stopped
+	if server.State != upcloud.ServerStateStopped {
+		if err := c.stopServer(ctx, server.UUID); err != nil {
+			return "", err
+		}
+	}
+
+	_, rootStorage, err := c.getRootStorage(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	template, err := c.service.TemplatizeStorage(ctx, &request.TemplatizeStorageRequest{
+		UUID:  rootStorage.UUID,
+		Title: title,
+	})
+	if err != nil {
+		return "", WrapError(err, "storage templatization")
+	}
+
+	// The server's storage is in maintenance while it is copied and cannot
+	// be deleted until the copy has finished
+	for _, uuid := range []string{template.UUID, rootStorage.UUID} {
+		if err := c.waitForStorageOnline(ctx, uuid); err != nil {
+			return "", err
+		}
+	}
+
+	if len(labels) > 0 {
+		templateLabels := make([]upcloud.Label, 0, len(labels))
+		for key, value := range labels {
+			templateLabels = append(templateLabels, upcloud.Label{Key: key, Value: value})
+		}
+		_, err = c.service.ModifyStorage(ctx, &request.ModifyStorageRequest{
+			UUID:   template.UUID,
+			Labels: &templateLabels,
+		})
+		if err != nil {
+			return "", WrapError(err, "labelling template")
+		}
+	}
+
+	return template.UUID, nil
+}
+
+// validateEncryption checks that encrypted storage can be created from a
+// template. The zone is left to the API, since the catalog's list of zones
+// offering encryption may lag behind UpCloud.
+func (c *Client) validateEncryption(ctx context.Context, templateUUID string) error {
+	template, err := c.service.GetStorageDetails(ctx, &request.GetStorageDetailsRequest{
+		UUID: templateUUID,
+	})
+	if err != nil {
+		return WrapError(err, "template lookup")
+	}
+
+	// Only cloud-init templates can be cloned into encrypted storage
+	if template.TemplateType != upcloud.StorageTemplateTypeCloudInit {
+		return &ProviderError{
+			Type:    ErrorTypeInvalidParameter,
+			Message: fmt.Sprintf("Template %s (%s) does not support storage encryption", template.Title, templateUUID),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package upcloud
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+)
+
+func TestValidateEncryption(t *testing.T) {
+	fake := newFakeService()
+	fake.storages["cloud-init"] = &upcloud.StorageDetails{
+		Storage: upcloud.Storage{UUID: "cloud-init", Title: "Ubuntu Server 24.04 LTS", Type: upcloud.StorageTypeTemplate, TemplateType: upcloud.StorageTemplateTypeCloudInit},
+	}
+	fake.storages["native"] = &upcloud.StorageDetails{
+		Storage: upcloud.Storage{UUID: "native", Title: "Windows Server 2022", Type: upcloud.StorageTypeTemplate, TemplateType: upcloud.StorageTemplateTypeNative},
+	}
+	client := fake.client()
+
+	if err := client.validateEncryption(context.Background(), "cloud-init"); err != nil {
+		t.Errorf("validateEncryption(cloud-init) error = %v", err)
+	}
+	err := client.validateEncryption(context.Background(), "native")
+	if err == nil || !strings.Contains(err.Error(), "does not support storage encryption") {
+		t.Errorf("validateEncryption(native) error = %v, want template not supported", err)
+	}
+	if err := client.validateEncryption(context.Background(), "missing"); err == nil {
+		t.Error("validateEncryption(missing) error = nil, want lookup error")
+	}
+}
+
+func TestCreateTemplateWaitsForServerStorage(t *testing.T) {
+	fake := newFakeService()
+	fake.addServer("server-uuid", "devpod-image-builder", upcloud.ServerStateStarted, "root-uuid")
+	client := fake.client()
+
+	templateUUID, err := client.CreateTemplate(context.Background(), "devpod-image-builder", "devpod-ubuntu", map[string]string{LabelOSFamily: "ubuntu"})
+	if err != nil {
+		t.Fatalf("CreateTemplate() error = %v", err)
+	}
+	if templateUUID != "root-uuid-template" {
+		t.Errorf("CreateTemplate() = %s, want root-uuid-template", templateUUID)
+	}
+
+	// The builder and its disk can be deleted right away
+	if err := client.DeleteWithStorages(context.Background(), "devpod-image-builder"); err != nil {
+		t.Fatalf("DeleteWithStorages() error = %v", err)
+	}
+	if _, ok := fake.storages["root-uuid"]; ok {
+		t.Error("builder disk was not deleted")
+	}
+	if _, ok := fake.storages[templateUUID]; !ok {
+		t.Error("template was deleted with the builder")
+	}
+}
+
+func TestResizeStorage(t *testing.T) {
+	busy := &upcloud.Problem{Type: "SERVER_STATE_ILLEGAL", Title: "The server is busy", Status: 409}
+	attached := &upcloud.Problem{Type: "STORAGE_ATTACHED", Title: "The storage is attached to a running server", Status: 400}
+
+	tests := []struct {
+		name        string
+		state       string
+		size        int
+		modifyErrs  []error
+		wantErr     bool
+		wantSize    int
+		wantStopped bool
+		wantState   string
+	}{
+		{"Online resize", upcloud.ServerStateStarted, 80, nil, false, 80, false, upcloud.ServerStateStarted},
+		{"Busy running server", upcloud.ServerStateStarted, 80, []error{busy}, false, 80, true, upcloud.ServerStateStarted},
+		{"Other error on a running server", upcloud.ServerStateStarted, 80, []error{attached}, false, 80, true, upcloud.ServerStateStarted},
+		{"Failing with the server stopped", upcloud.ServerStateStarted, 80, []error{busy, attached}, true, 50, true, upcloud.ServerStateStarted},
+		{"Stopped server", upcloud.ServerStateStopped, 80, nil, false, 80, false, upcloud.ServerStateStopped},
+		{"Error on a stopped server", upcloud.ServerStateStopped, 80, []error{attached}, true, 50, false, upcloud.ServerStateStopped},
+		{"Not larger", upcloud.ServerStateStarted, 50, nil, true, 50, false, upcloud.ServerStateStarted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeService()
+			server := fake.addServer("server-uuid", "devpod-workspace", tt.state, "root-uuid")
+			fake.failNext("ModifyStorage", tt.modifyErrs...)
+
+			err := fake.client().ResizeStorage(context.Background(), "devpod-workspace", tt.size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResizeStorage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := fake.storages["root-uuid"].Size; got != tt.wantSize {
+				t.Errorf("size = %d, want %d", got, tt.wantSize)
+			}
+			stopped := strings.Contains(strings.Join(fake.calls, ","), "StopServer")
+			if stopped != tt.wantStopped {
+				t.Errorf("server stopped = %v, want %v (calls %v)", stopped, tt.wantStopped, fake.calls)
+			}
+			if server.State != tt.wantState {
+				t.Errorf("state = %s, want %s", server.State, tt.wantState)
+			}
+		})
+	}
+}
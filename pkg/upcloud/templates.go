@@ -0,0 +1,369 @@
+package upcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud/request"
+)
+
+// TemplateCacheTTL is how long the public template list is cached on disk
+const TemplateCacheTTL = 24 * time.Hour
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	tokenPattern = regexp.MustCompile(`[a-z0-9.]+`)
+)
+
+// TemplateInfo describes an UpCloud OS template
+type TemplateInfo struct {
+	UUID         string `json:"uuid" yaml:"uuid"`
+	Title        string `json:"title" yaml:"title"`
+	Access       string `json:"access" yaml:"access"`
+	TemplateType string `json:"template_type" yaml:"template_type"`
+	Size         int    `json:"size_gb" yaml:"size_gb"`
+	Zone         string `json:"zone,omitempty" yaml:"zone,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// templateCache is the on-disk format of the cached public template list
+type templateCache struct {
+	FetchedAt time.Time      `json:"fetched_at"`
+	Templates []TemplateInfo `json:"templates"`
+}
+
+// IsUUID checks if a string is a storage UUID
+func IsUUID(value string) bool {
+	return uuidPattern.MatchString(strings.ToLower(value))
+}
+
+// ResolveImage resolves an image name such as "Ubuntu Server 24.04 LTS (Noble
+// Numbat)" or "ubuntu 24.04" to a public template UUID. The public template
+// list comes from the storage API and is cached on disk; the embedded
+// ImageMap is only used when neither the API nor a cache is available.
+func (c *Client) ResolveImage(ctx context.Context, imageName string) (string, error) {
+	template, err := c.resolveImageTemplate(ctx, imageName, "")
+	if err != nil {
+		return "", err
+	}
+
+	return template.UUID, nil
+}
+
+// ResolveTemplate returns the template a server will be created from: the
+// validated custom template if one is set, otherwise the resolved image
+func (c *Client) ResolveTemplate(ctx context.Context, imageName, templateUUID, zone string) (*TemplateInfo, error) {
+	if templateUUID != "" {
+		return c.ValidateTemplate(ctx, templateUUID, zone)
+	}
+
+	return c.resolveImageTemplate(ctx, imageName, zone)
+}
+
+// resolveImageTemplate resolves an image name to a public template. An image
+// given as a UUID is looked up, so its title and labels identify the OS.
+func (c *Client) resolveImageTemplate(ctx context.Context, imageName, zone string) (*TemplateInfo, error) {
+	templates, err := c.GetPublicTemplates(ctx)
+	if IsUUID(imageName) {
+		for _, template := range templates {
+			if strings.EqualFold(template.UUID, imageName) {
+				return &template, nil
+			}
+		}
+		return c.ValidateTemplate(ctx, imageName, zone)
+	}
+
+	if err != nil || len(templates) == 0 {
+		// Offline fallback
+		templateUUID, err := MapImageToTemplate(imageName)
+		if err != nil {
+			return nil, err
+		}
+		return &TemplateInfo{
+			UUID:         templateUUID,
+			Title:        imageName,
+			Access:       upcloud.StorageAccessPublic,
+			TemplateType: upcloud.StorageTemplateTypeCloudInit,
+		}, nil
+	}
+
+	return MatchTemplate(templates, imageName)
+}
+
+// GetPublicTemplates returns UpCloud's public OS templates. A fresh on-disk
+// cache is used when available, and a stale one if the API cannot be reached.
+func (c *Client) GetPublicTemplates(ctx context.Context) ([]TemplateInfo, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Using embedded template list\n")
+		return embeddedTemplates(), nil
+	}
+
+	cache, cacheErr := loadTemplateCache()
+	if cacheErr == nil && time.Since(cache.FetchedAt) < TemplateCacheTTL {
+		return cache.Templates, nil
+	}
+
+	templates, err := c.getTemplates(ctx, upcloud.StorageAccessPublic)
+	if err != nil {
+		if cacheErr == nil {
+			return cache.Templates, nil
+		}
+		return nil, err
+	}
+
+	// Caching is best effort
+	_ = saveTemplateCache(&templateCache{
+		FetchedAt: time.Now(),
+		Templates: templates,
+	})
+
+	return templates, nil
+}
+
+// GetPrivateTemplates returns the templates owned by the account
+func (c *Client) GetPrivateTemplates(ctx context.Context) ([]TemplateInfo, error) {
+	// Check for test mode
+	if c.service == nil {
+		return []TemplateInfo{}, nil
+	}
+
+	return c.getTemplates(ctx, upcloud.StorageAccessPrivate)
+}
+
+// getTemplates lists templates with the given access type
+func (c *Client) getTemplates(ctx context.Context, access string) ([]TemplateInfo, error) {
+	storages, err := c.service.GetStorages(ctx, &request.GetStoragesRequest{
+		Access: access,
+		Type:   upcloud.StorageTypeTemplate,
+	})
+	if err != nil {
+		return nil, WrapError(err, "listing templates")
+	}
+
+	templates := make([]TemplateInfo, 0, len(storages.Storages))
+	for i := range storages.Storages {
+		templates = append(templates, *newTemplateInfo(&storages.Storages[i]))
+	}
+
+	return templates, nil
+}
+
+// newTemplateInfo converts an UpCloud storage into a TemplateInfo
+func newTemplateInfo(storage *upcloud.Storage) *TemplateInfo {
+	info := &TemplateInfo{
+		UUID:         storage.UUID,
+		Title:        storage.Title,
+		Access:       storage.Access,
+		TemplateType: storage.TemplateType,
+		Size:         storage.Size,
+		Zone:         storage.Zone,
+	}
+
+	if len(storage.Labels) > 0 {
+		info.Labels = make(map[string]string, len(storage.Labels))
+		for _, label := range storage.Labels {
+			info.Labels[label.Key] = label.Value
+		}
+	}
+
+	return info
+}
+
+// ValidateTemplate checks that a template UUID exists, is a template the
+// account can use and is available in the zone. Problems are reported as
+// ErrorTypeInvalidParameter with a hint on how to fix them.
+func (c *Client) ValidateTemplate(ctx context.Context, templateUUID, zone string) (*TemplateInfo, error) {
+	if !IsUUID(templateUUID) {
+		return nil, &ProviderError{
+			Type:    ErrorTypeInvalidParameter,
+			Message: fmt.Sprintf("Template %q is not a valid UUID", templateUUID),
+			Hint:    "run 'images' to list available templates",
+		}
+	}
+
+	// Check for test mode
+	if c.service == nil {
+		return &TemplateInfo{
+			UUID:         templateUUID,
+			Title:        "Test template",
+			Access:       upcloud.StorageAccessPublic,
+			TemplateType: upcloud.StorageTemplateTypeCloudInit,
+		}, nil
+	}
+
+	storage, err := c.service.GetStorageDetails(ctx, &request.GetStorageDetailsRequest{
+		UUID: templateUUID,
+	})
+	if err != nil {
+		wrapped := WrapError(err, "template lookup")
+		perr, ok := wrapped.(*ProviderError)
+		if !ok {
+			return nil, wrapped
+		}
+
+		switch perr.Type {
+		case ErrorTypeNotFound:
+			return nil, &ProviderError{
+				Type:    ErrorTypeInvalidParameter,
+				Message: fmt.Sprintf("Template %s does not exist", templateUUID),
+				Err:     err,
+				Hint:    "check the UUID or run 'images --private' to list your templates",
+			}
+		case ErrorTypePermissionDenied:
+			return nil, &ProviderError{
+				Type:    ErrorTypeInvalidParameter,
+				Message: fmt.Sprintf("Template %s is not accessible by this account", templateUUID),
+				Err:     err,
+				Hint:    "private templates can only be used by the account that owns them and its sub-accounts with storage permissions",
+			}
+		default:
+			return nil, wrapped
+		}
+	}
+
+	if storage.Type != upcloud.StorageTypeTemplate {
+		return nil, &ProviderError{
+			Type:    ErrorTypeInvalidParameter,
+			Message: fmt.Sprintf("Storage %s (%s) is a %s storage, not a template", storage.Title, templateUUID, storage.Type),
+			Hint:    "create a template with 'image build' or by templatizing the storage",
+		}
+	}
+
+	// Private templates live in a single zone, public ones are available everywhere
+	if storage.Access == upcloud.StorageAccessPrivate && zone != "" && storage.Zone != zone {
+		return nil, &ProviderError{
+			Type:    ErrorTypeInvalidParameter,
+			Message: fmt.Sprintf("Template %s is in zone %s, but the workspace is created in %s", storage.Title, storage.Zone, zone),
+			Hint:    fmt.Sprintf("set UPCLOUD_ZONE=%s or build the template in %s", storage.Zone, zone),
+		}
+	}
+
+	return newTemplateInfo(&storage.Storage), nil
+}
+
+// MatchTemplate finds the template matching an image name. An exact title
+// match wins; otherwise every word of the name must be a prefix of a word in
+// the title, so "ubuntu 24.04" matches "Ubuntu Server 24.04 LTS (Noble Numbat)".
+func MatchTemplate(templates []TemplateInfo, imageName string) (*TemplateInfo, error) {
+	for i := range templates {
+		if strings.EqualFold(templates[i].Title, imageName) {
+			return &templates[i], nil
+		}
+	}
+
+	query := tokenize(imageName)
+	if len(query) == 0 {
+		return nil, fmt.Errorf("unknown image: %s", imageName)
+	}
+
+	var matches []*TemplateInfo
+	for i := range templates {
+		if matchesTokens(tokenize(templates[i].Title), query) {
+			matches = append(matches, &templates[i])
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("unknown image: %s (use 'images' command to list available images)", imageName)
+	case 1:
+		return matches[0], nil
+	default:
+		titles := make([]string, 0, len(matches))
+		for _, match := range matches {
+			titles = append(titles, match.Title)
+		}
+		sort.Strings(titles)
+		return nil, fmt.Errorf("image %q is ambiguous, it matches: %s", imageName, strings.Join(titles, ", "))
+	}
+}
+
+// tokenize splits a template title into lowercase words, keeping version numbers intact
+func tokenize(value string) []string {
+	return tokenPattern.FindAllString(strings.ToLower(value), -1)
+}
+
+// matchesTokens checks that every query token is a prefix of some title token
+func matchesTokens(title, query []string) bool {
+	for _, q := range query {
+		found := false
+		for _, t := range title {
+			if strings.HasPrefix(t, q) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// embeddedTemplates returns the templates from the embedded ImageMap
+func embeddedTemplates() []TemplateInfo {
+	templates := make([]TemplateInfo, 0, len(ImageMap))
+	for title, uuid := range ImageMap {
+		templates = append(templates, TemplateInfo{
+			UUID:         uuid,
+			Title:        title,
+			Access:       upcloud.StorageAccessPublic,
+			TemplateType: upcloud.StorageTemplateTypeCloudInit,
+		})
+	}
+	return templates
+}
+
+// templateCachePath returns the location of the template cache file
+func templateCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "devpod-provider-upcloud", "templates.json"), nil
+}
+
+func loadTemplateCache() (*templateCache, error) {
+	path, err := templateCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &templateCache{}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+func saveTemplateCache(cache *templateCache) error {
+	path, err := templateCachePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
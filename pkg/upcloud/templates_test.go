@@ -0,0 +1,128 @@
+package upcloud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+)
+
+func TestMatchTemplate(t *testing.T) {
+	templates := []TemplateInfo{
+		{UUID: TemplateUbuntu2404, Title: "Ubuntu Server 24.04 LTS (Noble Numbat)"},
+		{UUID: TemplateUbuntu2204, Title: "Ubuntu Server 22.04 LTS (Jammy Jellyfish)"},
+		{UUID: TemplateDebian12, Title: "Debian GNU/Linux 12 (Bookworm)"},
+		{UUID: TemplateRocky9, Title: "Rocky Linux 9"},
+		{UUID: TemplateAlma9, Title: "AlmaLinux 9"},
+	}
+
+	tests := []struct {
+		name      string
+		imageName string
+		want      string
+		wantError bool
+	}{
+		{"Exact title", "Ubuntu Server 24.04 LTS (Noble Numbat)", TemplateUbuntu2404, false},
+		{"Case insensitive title", "rocky linux 9", TemplateRocky9, false},
+		{"Name and version", "ubuntu 24.04", TemplateUbuntu2404, false},
+		{"Codename", "jammy", TemplateUbuntu2204, false},
+		{"Word prefix", "alma 9", TemplateAlma9, false},
+		{"Distribution and version", "debian 12", TemplateDebian12, false},
+		{"Ambiguous", "ubuntu", "", true},
+		{"Unknown", "windows", "", true},
+		{"Empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchTemplate(templates, tt.imageName)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("Expected error for image %q, got %s", tt.imageName, got.UUID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error for image %q: %v", tt.imageName, err)
+			}
+			if got.UUID != tt.want {
+				t.Errorf("MatchTemplate(%q) = %s, want %s", tt.imageName, got.UUID, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsUUID(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"Public template", TemplateUbuntu2404, true},
+		{"Private template", "0145ab2c-1a2b-4c3d-9e8f-0123456789ab", true},
+		{"Prefix only", "01000000-", false},
+		{"Image name", "Ubuntu Server 24.04 LTS (Noble Numbat)", false},
+		{"Empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUUID(tt.value); got != tt.want {
+				t.Errorf("IsUUID(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveImageTemplateUUID(t *testing.T) {
+	// Keep the public template list out of the user's cache
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	const privateUUID = "0145ab2c-1a2b-4c3d-9e8f-0123456789ab"
+	fake := newFakeService()
+	fake.storages[TemplateUbuntu2404] = &upcloud.StorageDetails{Storage: upcloud.Storage{
+		UUID: TemplateUbuntu2404, Title: "Ubuntu Server 24.04 LTS (Noble Numbat)",
+		Type: upcloud.StorageTypeTemplate, Access: upcloud.StorageAccessPublic, TemplateType: upcloud.StorageTemplateTypeCloudInit,
+	}}
+	fake.storages[privateUUID] = &upcloud.StorageDetails{Storage: upcloud.Storage{
+		UUID: privateUUID, Title: "devpod-workspace", Zone: "de-fra1",
+		Type: upcloud.StorageTypeTemplate, Access: upcloud.StorageAccessPrivate, TemplateType: upcloud.StorageTemplateTypeCloudInit,
+		Labels: upcloud.LabelSlice{{Key: LabelOSFamily, Value: string(OSFamilyDebian)}},
+	}}
+
+	tests := []struct {
+		name       string
+		image      string
+		zone       string
+		wantTitle  string
+		wantFamily OSFamily
+		wantErr    bool
+	}{
+		{"Public template", TemplateUbuntu2404, "de-fra1", "Ubuntu Server 24.04 LTS (Noble Numbat)", OSFamilyUbuntu, false},
+		{"Private template identified by its labels", privateUUID, "de-fra1", "devpod-workspace", OSFamilyDebian, false},
+		{"Private template in another zone", privateUUID, "fi-hel1", "", "", true},
+		{"Unknown template", "0145ab2c-0000-4000-8000-000000000000", "de-fra1", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := fake.client().ResolveTemplate(context.Background(), tt.image, "", tt.zone)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ResolveTemplate() = %+v, want an error", template)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveTemplate() error = %v", err)
+			}
+			if template.UUID != tt.image || template.Title != tt.wantTitle {
+				t.Errorf("ResolveTemplate() = %+v, want %s titled %q", template, tt.image, tt.wantTitle)
+			}
+			if family := DetectTemplateOS(template).Family; family != tt.wantFamily {
+				t.Errorf("DetectTemplateOS() family = %s, want %s", family, tt.wantFamily)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package upcloud
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/UpCloudLtd/upcloud-go-api/v8/upcloud"
+	"github.com/neuralmux/devpod-provider-upcloud/pkg/config"
+)
+
+// zoneCountries maps the country code zone IDs start with to the country name
+var zoneCountries = map[string]string{
+	"au": "Australia",
+	"de": "Germany",
+	"dk": "Denmark",
+	"es": "Spain",
+	"fi": "Finland",
+	"nl": "Netherlands",
+	"no": "Norway",
+	"pl": "Poland",
+	"se": "Sweden",
+	"sg": "Singapore",
+	"uk": "United Kingdom",
+	"us": "United States",
+}
+
+// ZoneInfo describes an UpCloud zone
+type ZoneInfo struct {
+	ID          string `json:"id" yaml:"id"`
+	Description string `json:"description" yaml:"description"`
+	Country     string `json:"country" yaml:"country"`
+	Public      bool   `json:"public" yaml:"public"`
+	ParentZone  string `json:"parent_zone,omitempty" yaml:"parent_zone,omitempty"`
+}
+
+// ZoneCountry returns the country of a zone from its ID, such as Germany for
+// de-fra1, or an empty string for unknown country codes
+func ZoneCountry(zone string) string {
+	code, _, _ := strings.Cut(zone, "-")
+	return zoneCountries[code]
+}
+
+// GetZones returns the zones available to the account, sorted by ID
+func (c *Client) GetZones(ctx context.Context) ([]ZoneInfo, error) {
+	// Check for test mode
+	if c.service == nil {
+		fmt.Fprintf(os.Stderr, "Test mode: Using embedded region list\n")
+		plans, err := config.LoadServerPlans()
+		if err != nil {
+			return nil, err
+		}
+		var zones []ZoneInfo
+		for _, region := range plans.GetRegions() {
+			zones = append(zones, ZoneInfo{ID: region, Description: region, Country: ZoneCountry(region), Public: true})
+		}
+		sort.Slice(zones, func(i, j int) bool { return zones[i].ID < zones[j].ID })
+		return zones, nil
+	}
+
+	response, err := c.service.GetZones(ctx)
+	if err != nil {
+		return nil, WrapError(err, "listing zones")
+	}
+
+	zones := make([]ZoneInfo, 0, len(response.Zones))
+	for _, zone := range response.Zones {
+		zones = append(zones, ZoneInfo{
+			ID:          zone.ID,
+			Description: zone.Description,
+			Country:     ZoneCountry(zone.ID),
+			Public:      zone.Public == upcloud.True,
+			ParentZone:  zone.ParentZone,
+		})
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].ID < zones[j].ID })
+
+	return zones, nil
+}